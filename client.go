@@ -1,10 +1,12 @@
 package modbus
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/GoAethereal/cancel"
 )
@@ -22,9 +24,29 @@ import (
 //	//use the client`s read/write methods like c.ReadCoils, etc
 type Client struct {
 	Config
-	mtx sync.Mutex
-	c   connection
-	f   framer
+	// Timeout limits the time waiting for the response to a request, which then fails with a *TimeoutError.
+	// It applies to each attempt and can be overridden per request by WithTimeout.
+	// By default a request waits until its context is canceled.
+	Timeout time.Duration
+	// MaxInFlight limits the number of concurrent transactions in tcp mode, further requests are queued.
+	// By default it´s unlimited. In rtu and ascii mode only a single transaction is allowed on the line.
+	MaxInFlight int
+	// Retry defines how failed requests are repeated, by default no retries are made.
+	Retry Retry
+	// Reconnect defines how a lost connection is re-established, by default it´s re-dialed on the next request.
+	Reconnect Reconnect
+	// Order is the byte order of values spanning multiple registers, as used by the typed methods like ReadFloat32s.
+	// It can be overridden per request by WithOrder, by default it´s ABCD (big endian).
+	Order Order
+	// MaxGap is the number of unmapped registers or bits, which Unmarshal and ReadPoints read along in order to
+	// fetch the surrounding values by a single request. By default only adjacent values are fetched together,
+	// as devices may reject reads of unmapped addresses.
+	MaxGap int
+	mtx    sync.Mutex
+	c      connection
+	f      framer
+	sv     supervisor
+	w      window
 }
 
 func (c *Client) Ready() bool {
@@ -36,26 +58,33 @@ func (c *Client) Ready() bool {
 	return false
 }
 
-// Disconnect shuts down the connection.
+// Disconnect shuts down the connection and stops its supervision.
 // All running requests will be canceled as a result.
 func (c *Client) Disconnect() {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
+	if c.sv.stop != nil {
+		c.sv.stop.Cancel()
+		c.sv.stop = nil
+	}
+	c.sv.attempt, c.sv.next, c.sv.err = 0, time.Time{}, nil
 	if c.c != nil {
 		c.c.close()
+		c.c = nil
+		c.sv.notify(State{Status: Disconnected})
 	}
 }
 
 func (c *Client) init(ctx cancel.Context) (_ connection, _ framer, err error) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	if c.c == nil || !c.c.ready() {
-		if c.c, err = c.Config.connection(ctx); err != nil {
+	if c.f == nil {
+		if c.f, err = c.Config.framer(ctx); err != nil {
 			return nil, nil, err
 		}
 	}
-	if c.f == nil {
-		if c.f, err = c.Config.framer(ctx); err != nil {
+	if c.c == nil || !c.c.ready() {
+		if err = c.dial(ctx); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -65,9 +94,47 @@ func (c *Client) init(ctx cancel.Context) (_ connection, _ framer, err error) {
 // Request encodes the request into a valid application data unit and sends it to the clients endpoint.
 // Only function codes below 0x80 are accepted.
 // The method will return a nil response and an error if something went wrong.
+// Broadcasts (unit id 0 in rtu or ascii mode) aren´t answered, instead the turnaround delay is awaited
+// and a nil response without error is returned. Reading function codes can´t be broadcast,
+// they are rejected with ErrInvalidParameter before anything is sent.
+// Failed requests are repeated as defined by the clients Retry policy.
 func (c *Client) Request(ctx cancel.Context, uid, code byte, req []byte) (res []byte, err error) {
-	if code == 0 || code >= 0x80 {
+	switch {
+	case code == 0 || code >= 0x80:
 		return nil, IllegalFunction
+	case c.broadcast(uid) && reads(code):
+		return nil, ErrInvalidParameter
+	}
+	for attempt := 1; ; attempt++ {
+		if res, err = c.request(ctx, uid, code, req); err == nil || !c.Retry.retries(attempt, code, req, err) {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, context.Canceled
+		case <-time.After(c.Retry.backoff(attempt)):
+		}
+	}
+}
+
+// request executes a single attempt of the request.
+func (c *Client) request(ctx cancel.Context, uid, code byte, req []byte) (res []byte, err error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if c.broadcast(uid) {
+		if err := c.transmit(ctx, uid, code, req); err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, context.Canceled
+		case <-time.After(c.turnaround()):
+			return nil, nil
+		}
 	}
 
 	con, f, err := c.init(ctx)
@@ -81,10 +148,16 @@ func (c *Client) Request(ctx cancel.Context, uid, code byte, req []byte) (res []
 
 	sig := cancel.New().Propagate(ctx)
 	defer sig.Cancel()
+	timeout := c.timeout(ctx)
+	if timeout > 0 {
+		sig.Timeout(timeout)
+	}
 
+	answered := false
 	wait := con.rx(sig, func(adu []byte, er error) (quit bool) {
 		if er != nil {
 			res, err = nil, er
+			answered = true
 			return true
 		}
 		e := f.verify(req, adu)
@@ -92,11 +165,12 @@ func (c *Client) Request(ctx cancel.Context, uid, code byte, req []byte) (res []
 		case nil:
 			//needs check for exceptions
 			_, _, res, err = f.decode(req[:copy(req[:cap(req)], adu)])
-		case ErrMismatchedTransactionId:
+		case ErrMismatchedTransactionId, ErrMismatchedFunctionCode:
 			return false
 		default:
 			res, err = nil, e
 		}
+		answered = true
 		return true
 	})
 
@@ -112,8 +186,84 @@ func (c *Client) Request(ctx cancel.Context, uid, code byte, req []byte) (res []
 	case <-ctx.Done():
 		return nil, context.Canceled
 	default:
-		return res, err
 	}
+	if !answered {
+		if c.inFlight() == 1 {
+			// the late response of the device is dropped, before the next request could mistake it for its own
+			select {
+			case <-ctx.Done():
+				return nil, context.Canceled
+			case <-time.After(c.turnaround()):
+			}
+		}
+		return nil, &TimeoutError{Duration: timeout}
+	}
+	return res, err
+}
+
+// inFlight returns the maximum number of concurrent transactions, zero if unlimited.
+func (c *Client) inFlight() int {
+	if c.Mode == "rtu" || c.Mode == "ascii" {
+		return 1
+	}
+	if c.MaxInFlight > 0 {
+		return c.MaxInFlight
+	}
+	return 0
+}
+
+// acquire awaits a free slot for a transaction, if the number of concurrent transactions is limited.
+// The returned function frees the slot once the transaction is finished.
+func (c *Client) acquire(ctx cancel.Context) (release func(), err error) {
+	limit := c.inFlight()
+	if limit == 0 {
+		return func() {}, nil
+	}
+	if err := c.w.acquire(ctx, limit); err != nil {
+		return nil, err
+	}
+	return c.w.release, nil
+}
+
+// WithTimeout overrides the response timeout of the client for the requests issued with the returned context.
+// A zero duration disables the timeout.
+func WithTimeout(ctx cancel.Context, d time.Duration) context.Context {
+	return withValue(ctx, timeoutKey, d)
+}
+
+// WithOrder overrides the byte order of the client for the typed requests issued with the returned context.
+func WithOrder(ctx cancel.Context, order Order) context.Context {
+	return withValue(ctx, orderKey, order)
+}
+
+// timeout returns the response timeout applying to requests issued with the given context.
+func (c *Client) timeout(ctx cancel.Context) time.Duration {
+	if d, ok := value(ctx, timeoutKey).(time.Duration); ok {
+		return d
+	}
+	return c.Timeout
+}
+
+// send encodes the request and transmits it without awaiting a response.
+func (c *Client) send(ctx cancel.Context, uid, code byte, req []byte) (err error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.transmit(ctx, uid, code, req)
+}
+
+// transmit encodes and sends the request, the transaction must have been acquired beforehand.
+func (c *Client) transmit(ctx cancel.Context, uid, code byte, req []byte) (err error) {
+	con, f, err := c.init(ctx)
+	if err != nil {
+		return err
+	}
+	if req, err = f.encode(uid, code, req); err != nil {
+		return err
+	}
+	return con.tx(ctx, req)
 }
 
 // ReadCoils requests 1 to 2000 (quantity) contiguous coil states, starting from address.
@@ -186,6 +336,8 @@ func (c *Client) WriteSingleCoil(ctx cancel.Context, uid byte, address uint16, s
 	switch {
 	case err != nil:
 		return err
+	case c.broadcast(uid):
+		return nil
 	case len(res) != 4 || binary.BigEndian.Uint16(res) != address:
 		return SlaveDeviceFailure
 	}
@@ -198,12 +350,191 @@ func (c *Client) WriteSingleRegister(ctx cancel.Context, uid byte, address, valu
 	switch {
 	case err != nil:
 		return err
+	case c.broadcast(uid):
+		return nil
 	case len(res) != 4 || binary.BigEndian.Uint16(res) != address || binary.BigEndian.Uint16(res[2:]) != value:
 		return SlaveDeviceFailure
 	}
 	return nil
 }
 
+// ReadExceptionStatus reads the contents of the eight exception status outputs of a remote device.
+// The meaning of the individual bits is device specific.
+func (c *Client) ReadExceptionStatus(ctx cancel.Context, uid byte) (status byte, err error) {
+	res, err := c.Request(ctx, uid, 0x07, nil)
+	switch {
+	case err != nil:
+		return 0, err
+	case len(res) != 1:
+		return 0, SlaveDeviceFailure
+	}
+	return res[0], nil
+}
+
+// Diagnostics executes the diagnostic sub-function with the given data.
+// On success the data of the response, without the echoed sub-function, is returned.
+// For the common sub-functions the typed methods like ReturnQueryData are preferable.
+func (c *Client) Diagnostics(ctx cancel.Context, uid byte, sub uint16, data []byte) (res []byte, err error) {
+	res, err = c.Request(ctx, uid, 0x08, put(2+len(data), sub, data))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(res) < 2 || binary.BigEndian.Uint16(res) != sub:
+		return nil, SlaveDeviceFailure
+	}
+	return res[2:], nil
+}
+
+// diagnose executes a diagnostic sub-function with a single value and verifies the echo of the response.
+func (c *Client) diagnose(ctx cancel.Context, uid byte, sub, value uint16) (err error) {
+	res, err := c.Diagnostics(ctx, uid, sub, put(2, value))
+	switch {
+	case err != nil:
+		return err
+	case len(res) != 2 || binary.BigEndian.Uint16(res) != value:
+		return SlaveDeviceFailure
+	}
+	return nil
+}
+
+// counter reads the value returned by a diagnostic sub-function.
+func (c *Client) counter(ctx cancel.Context, uid byte, sub uint16) (count uint16, err error) {
+	res, err := c.Diagnostics(ctx, uid, sub, put(2, uint16(0)))
+	switch {
+	case err != nil:
+		return 0, err
+	case len(res) != 2:
+		return 0, SlaveDeviceFailure
+	}
+	return binary.BigEndian.Uint16(res), nil
+}
+
+// ReturnQueryData (sub-function 0x00) sends the data to the remote device, which is expected to echo it.
+func (c *Client) ReturnQueryData(ctx cancel.Context, uid byte, data []byte) (err error) {
+	res, err := c.Diagnostics(ctx, uid, 0x00, data)
+	switch {
+	case err != nil:
+		return err
+	case !bytes.Equal(res, data):
+		return SlaveDeviceFailure
+	}
+	return nil
+}
+
+// RestartCommunications (sub-function 0x01) restarts the serial line port of the remote device
+// and clears its counters, the communication event log is cleared if clearLog is set.
+// A device in listen only mode leaves it but doesn´t respond, hence the context should limit the wait.
+func (c *Client) RestartCommunications(ctx cancel.Context, uid byte, clearLog bool) (err error) {
+	if clearLog {
+		return c.diagnose(ctx, uid, 0x01, 0xFF00)
+	}
+	return c.diagnose(ctx, uid, 0x01, 0x0000)
+}
+
+// ReturnDiagnosticRegister (sub-function 0x02) returns the content of the diagnostic register.
+func (c *Client) ReturnDiagnosticRegister(ctx cancel.Context, uid byte) (register uint16, err error) {
+	return c.counter(ctx, uid, 0x02)
+}
+
+// ChangeASCIIInputDelimiter (sub-function 0x03) replaces the line feed terminating ascii frames
+// sent to the remote device by the given delimiter.
+func (c *Client) ChangeASCIIInputDelimiter(ctx cancel.Context, uid byte, delimiter byte) (err error) {
+	return c.diagnose(ctx, uid, 0x03, uint16(delimiter)<<8)
+}
+
+// ForceListenOnlyMode (sub-function 0x04) puts the remote device into listen only mode.
+// The device will neither execute nor answer any request until it´s restarted by RestartCommunications.
+// As no response is returned the method only awaits the transmission of the request.
+func (c *Client) ForceListenOnlyMode(ctx cancel.Context, uid byte) (err error) {
+	return c.send(ctx, uid, 0x08, put(4, uint16(0x04), uint16(0)))
+}
+
+// ClearCounters (sub-function 0x0A) clears all counters and the diagnostic register of the remote device.
+func (c *Client) ClearCounters(ctx cancel.Context, uid byte) (err error) {
+	return c.diagnose(ctx, uid, 0x0A, 0)
+}
+
+// ReturnBusMessageCount (sub-function 0x0B) returns the number of messages the remote device
+// detected on the bus since its last restart, clear counters operation, or power-up.
+func (c *Client) ReturnBusMessageCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x0B)
+}
+
+// ReturnBusCommunicationErrorCount (sub-function 0x0C) returns the number of checksum errors
+// encountered by the remote device.
+func (c *Client) ReturnBusCommunicationErrorCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x0C)
+}
+
+// ReturnBusExceptionErrorCount (sub-function 0x0D) returns the number of exception responses
+// returned by the remote device.
+func (c *Client) ReturnBusExceptionErrorCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x0D)
+}
+
+// ReturnSlaveMessageCount (sub-function 0x0E) returns the number of messages addressed to
+// the remote device or broadcast.
+func (c *Client) ReturnSlaveMessageCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x0E)
+}
+
+// ReturnSlaveNoResponseCount (sub-function 0x0F) returns the number of messages addressed to
+// the remote device for which it returned no response.
+func (c *Client) ReturnSlaveNoResponseCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x0F)
+}
+
+// ReturnSlaveNAKCount (sub-function 0x10) returns the number of negative acknowledge exceptions
+// returned by the remote device.
+func (c *Client) ReturnSlaveNAKCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x10)
+}
+
+// ReturnSlaveBusyCount (sub-function 0x11) returns the number of slave device busy exceptions
+// returned by the remote device.
+func (c *Client) ReturnSlaveBusyCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x11)
+}
+
+// ReturnBusCharacterOverrunCount (sub-function 0x12) returns the number of messages the remote
+// device could not handle due to a character overrun condition.
+func (c *Client) ReturnBusCharacterOverrunCount(ctx cancel.Context, uid byte) (count uint16, err error) {
+	return c.counter(ctx, uid, 0x12)
+}
+
+// ClearOverrunCounter (sub-function 0x14) clears the overrun error counter and resets the error flag.
+func (c *Client) ClearOverrunCounter(ctx cancel.Context, uid byte) (err error) {
+	return c.diagnose(ctx, uid, 0x14, 0)
+}
+
+// GetCommEventCounter requests the status word and the event counter of the remote device.
+// The status is 0xFFFF while a previous command is still processed, otherwise 0x0000.
+// As the counter is incremented for each successfully completed request, comparing it before and after
+// a request reveals whether the request was processed.
+func (c *Client) GetCommEventCounter(ctx cancel.Context, uid byte) (status, count uint16, err error) {
+	res, err := c.Request(ctx, uid, 0x0B, nil)
+	switch {
+	case err != nil:
+		return 0, 0, err
+	case len(res) != 4:
+		return 0, 0, SlaveDeviceFailure
+	}
+	return binary.BigEndian.Uint16(res), binary.BigEndian.Uint16(res[2:]), nil
+}
+
+// GetCommEventLog requests the status word, event counter, message count and the communication event log
+// of the remote device. The log holds up to 64 events, the most recent one first.
+func (c *Client) GetCommEventLog(ctx cancel.Context, uid byte) (status, count, messages uint16, events []byte, err error) {
+	res, err := c.Request(ctx, uid, 0x0C, nil)
+	switch {
+	case err != nil:
+		return 0, 0, 0, nil, err
+	case len(res) < 7 || int(res[0]) != len(res[1:]):
+		return 0, 0, 0, nil, SlaveDeviceFailure
+	}
+	return binary.BigEndian.Uint16(res[1:]), binary.BigEndian.Uint16(res[3:]), binary.BigEndian.Uint16(res[5:]), res[7:], nil
+}
+
 // WriteMultipleCoils sets the state of all coils starting at address to the value of status, where false=OFF and true=ON.
 // Status needs to be of length 1 to 1968.
 func (c *Client) WriteMultipleCoils(ctx cancel.Context, uid byte, address uint16, status ...bool) (err error) {
@@ -215,6 +546,8 @@ func (c *Client) WriteMultipleCoils(ctx cancel.Context, uid byte, address uint16
 	switch {
 	case err != nil:
 		return err
+	case c.broadcast(uid):
+		return nil
 	case binary.BigEndian.Uint16(res) != address || binary.BigEndian.Uint16(res[2:]) != quantity:
 		return SlaveDeviceFailure
 	}
@@ -236,12 +569,104 @@ func (c *Client) WriteMultipleRegisters(ctx cancel.Context, uid byte, address ui
 	switch {
 	case err != nil:
 		return err
+	case c.broadcast(uid):
+		return nil
 	case binary.BigEndian.Uint16(res) != address || binary.BigEndian.Uint16(res[2:]) != quantity:
 		return SlaveDeviceFailure
 	}
 	return nil
 }
 
+// ReportServerID requests the identity of the remote device.
+// Returned are the server id, the run indicator status and any additional device specific data.
+// The server id is expected to be a single byte, as is common for most devices.
+func (c *Client) ReportServerID(ctx cancel.Context, uid byte) (id byte, running bool, data []byte, err error) {
+	res, err := c.Request(ctx, uid, 0x11, nil)
+	switch {
+	case err != nil:
+		return 0, false, nil, err
+	case len(res) < 3 || int(res[0]) != len(res[1:]):
+		return 0, false, nil, SlaveDeviceFailure
+	}
+	switch res[2] {
+	case 0x00:
+	case 0xFF:
+		running = true
+	default:
+		return 0, false, nil, SlaveDeviceFailure
+	}
+	return res[1], running, res[3:], nil
+}
+
+// ReadFileRecord reads the referenced file records, each with a length of 1 or more registers.
+// Multiple records are read with a single request, their data is returned in the same order.
+// The request is limited to 35 records and the response to 245 bytes, where each record
+// takes up 2 bytes in addition to its register values.
+func (c *Client) ReadFileRecord(ctx cancel.Context, uid byte, records ...FileRecord) (data [][]byte, err error) {
+	req, ex := putFileRecords(records, false)
+	if ex != 0 {
+		return nil, ex
+	}
+	size := 0
+	for _, r := range records {
+		size += 2 + 2*int(r.Length)
+	}
+	if size > 0xF5 {
+		return nil, IllegalDataValue
+	}
+	res, err := c.Request(ctx, uid, 0x14, req)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(res) != 1+size || int(res[0]) != size:
+		return nil, SlaveDeviceFailure
+	}
+	data, res = make([][]byte, len(records)), res[1:]
+	for i, r := range records {
+		if int(res[0]) != 1+2*int(r.Length) || res[1] != 0x06 {
+			return nil, SlaveDeviceFailure
+		}
+		data[i], res = res[2:2+2*r.Length], res[2+2*r.Length:]
+	}
+	return data, nil
+}
+
+// WriteFileRecord writes the data of each given file record, the length of a record is implied by its data.
+// Multiple records are written with a single request, limited to 251 bytes where each record
+// takes up 7 bytes in addition to its register values.
+func (c *Client) WriteFileRecord(ctx cancel.Context, uid byte, records ...FileRecord) (err error) {
+	req, ex := putFileRecords(records, true)
+	if ex != 0 {
+		return ex
+	}
+	res, err := c.Request(ctx, uid, 0x15, req)
+	switch {
+	case err != nil:
+		return err
+	case c.broadcast(uid):
+		return nil
+	case !bytes.Equal(res, req):
+		return SlaveDeviceFailure
+	}
+	return nil
+}
+
+// MaskWriteRegister modifies the holding register at address by the combination of andMask and orMask.
+// The remote device computes the new value as (current AND andMask) OR (orMask AND (NOT andMask)),
+// hence individual bits are set or cleared without the race of a separate read and write.
+func (c *Client) MaskWriteRegister(ctx cancel.Context, uid byte, address, andMask, orMask uint16) (err error) {
+	res, err := c.Request(ctx, uid, 0x16, put(6, address, andMask, orMask))
+	switch {
+	case err != nil:
+		return err
+	case c.broadcast(uid):
+		return nil
+	case len(res) != 6 || binary.BigEndian.Uint16(res) != address || binary.BigEndian.Uint16(res[2:]) != andMask || binary.BigEndian.Uint16(res[4:]) != orMask:
+		return SlaveDeviceFailure
+	}
+	return nil
+}
+
 // ReadWriteMultipleRegisters reads a contiguous block of holding registers (rQuantity) from rAddress.
 // Also the values are written at wAddress.
 func (c *Client) ReadWriteMultipleRegisters(ctx cancel.Context, uid byte, rAddress, rQuantity, wAddress uint16, values []byte) (res []byte, err error) {
@@ -266,3 +691,71 @@ func (c *Client) ReadWriteMultipleRegisters(ctx cancel.Context, uid byte, rAddre
 	}
 	return res[1:], nil
 }
+
+// ReadFIFOQueue reads the content of the first-in-first-out queue of registers at address.
+// On success returns the queued register values, which are 0 to 31 registers in size.
+func (c *Client) ReadFIFOQueue(ctx cancel.Context, uid byte, address uint16) (values []byte, err error) {
+	res, err := c.Request(ctx, uid, 0x18, put(2, address))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(res) < 4 || int(binary.BigEndian.Uint16(res)) != len(res)-2:
+		return nil, SlaveDeviceFailure
+	}
+	if count := binary.BigEndian.Uint16(res[2:]); fifoCheck(int(count)) != 0 || 2*int(count) != len(res)-4 {
+		return nil, SlaveDeviceFailure
+	}
+	return res[4:], nil
+}
+
+// EncapsulatedInterfaceTransport tunnels the data of the given MEI type through the function code 0x2B.
+// On success returns the data of the response, without the echoed MEI type.
+func (c *Client) EncapsulatedInterfaceTransport(ctx cancel.Context, uid, mei byte, data []byte) (res []byte, err error) {
+	res, err = c.Request(ctx, uid, 0x2B, put(1+len(data), mei, data))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(res) < 1 || res[0] != mei:
+		return nil, SlaveDeviceFailure
+	}
+	return res[1:], nil
+}
+
+// ReadDeviceIdentification reads the identification objects of the remote device (function code 0x2B / MEI type 0x0E).
+// The code selects the access, either a stream of the basic, regular or extended objects starting at the object id,
+// or the individual object with the given id. Streams spread across multiple responses are followed until all
+// objects are collected. Stream access should normally start at object id 0x00.
+func (c *Client) ReadDeviceIdentification(ctx cancel.Context, uid, code, id byte) (objects DeviceIdentification, err error) {
+	if code < BasicDeviceIdentification || code > IndividualDeviceIdentification {
+		return nil, IllegalDataValue
+	}
+	objects = DeviceIdentification{}
+	for {
+		res, err := c.EncapsulatedInterfaceTransport(ctx, uid, 0x0E, []byte{code, id})
+		switch {
+		case err != nil:
+			return nil, err
+		case len(res) < 5 || res[0] != code || res[2] != 0x00 && res[2] != 0xFF:
+			return nil, SlaveDeviceFailure
+		}
+		more, next, list := res[2] == 0xFF, res[3], res[5:]
+		for i := 0; i < int(res[4]); i++ {
+			if len(list) < 2 || len(list) < 2+int(list[1]) {
+				return nil, SlaveDeviceFailure
+			}
+			objects[list[0]] = string(list[2 : 2+list[1]])
+			list = list[2+list[1]:]
+		}
+		if len(list) != 0 {
+			return nil, SlaveDeviceFailure
+		}
+		if !more || code == IndividualDeviceIdentification {
+			return objects, nil
+		}
+		// an already received object would lead to an endless loop
+		if _, ok := objects[next]; ok {
+			return nil, SlaveDeviceFailure
+		}
+		id = next
+	}
+}
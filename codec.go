@@ -0,0 +1,573 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// Order defines the arrangement of the bytes of values spanning multiple registers.
+// The letters name the bytes of a 32 bit value, from the most to the least significant one,
+// in the order of their transmission. 64 bit values are arranged alike.
+type Order int
+
+const (
+	// ABCD is the big endian order as used by the modbus specification for single registers.
+	ABCD Order = iota
+	// CDAB swaps the order of the registers (word swap).
+	CDAB
+	// BADC swaps the bytes inside each register (byte swap).
+	BADC
+	// DCBA is the little endian order.
+	DCBA
+)
+
+// String returns the name of the order.
+func (o Order) String() string {
+	switch o {
+	case ABCD:
+		return "ABCD"
+	case CDAB:
+		return "CDAB"
+	case BADC:
+		return "BADC"
+	case DCBA:
+		return "DCBA"
+	}
+	return "unknown"
+}
+
+// arrange converts the value b between big endian and the order, in place.
+// Both swaps are their own inverse, hence the same function serves encoding and decoding.
+func (o Order) arrange(b []byte) {
+	if o == BADC || o == DCBA {
+		for i := 0; i+1 < len(b); i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	if o == CDAB || o == DCBA {
+		for i, j := 0, len(b)-2; i < j; i, j = i+2, j-2 {
+			b[i], b[i+1], b[j], b[j+1] = b[j], b[j+1], b[i], b[i+1]
+		}
+	}
+}
+
+// Uint16 decodes the first 2 bytes (1 register) of b, only the byte swap applies.
+func (o Order) Uint16(b []byte) uint16 {
+	buf := []byte{b[0], b[1]}
+	o.arrange(buf)
+	return binary.BigEndian.Uint16(buf)
+}
+
+// PutUint16 encodes v into the first 2 bytes (1 register) of b, only the byte swap applies.
+func (o Order) PutUint16(b []byte, v uint16) {
+	binary.BigEndian.PutUint16(b, v)
+	o.arrange(b[:2])
+}
+
+// Uint32 decodes the first 4 bytes (2 registers) of b.
+func (o Order) Uint32(b []byte) uint32 {
+	buf := make([]byte, 4)
+	copy(buf, b)
+	o.arrange(buf)
+	return binary.BigEndian.Uint32(buf)
+}
+
+// PutUint32 encodes v into the first 4 bytes (2 registers) of b.
+func (o Order) PutUint32(b []byte, v uint32) {
+	binary.BigEndian.PutUint32(b, v)
+	o.arrange(b[:4])
+}
+
+// Uint64 decodes the first 8 bytes (4 registers) of b.
+func (o Order) Uint64(b []byte) uint64 {
+	buf := make([]byte, 8)
+	copy(buf, b)
+	o.arrange(buf)
+	return binary.BigEndian.Uint64(buf)
+}
+
+// PutUint64 encodes v into the first 8 bytes (4 registers) of b.
+func (o Order) PutUint64(b []byte, v uint64) {
+	binary.BigEndian.PutUint64(b, v)
+	o.arrange(b[:8])
+}
+
+// DecodeUint32s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeUint32s(b []byte) []uint32 {
+	values := make([]uint32, len(b)/4)
+	for i := range values {
+		values[i] = o.Uint32(b[4*i:])
+	}
+	return values
+}
+
+// EncodeUint32s encodes the values into register values.
+func (o Order) EncodeUint32s(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		o.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// DecodeInt32s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeInt32s(b []byte) []int32 {
+	values := make([]int32, len(b)/4)
+	for i := range values {
+		values[i] = int32(o.Uint32(b[4*i:]))
+	}
+	return values
+}
+
+// EncodeInt32s encodes the values into register values.
+func (o Order) EncodeInt32s(values ...int32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		o.PutUint32(b[4*i:], uint32(v))
+	}
+	return b
+}
+
+// DecodeFloat32s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeFloat32s(b []byte) []float32 {
+	values := make([]float32, len(b)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(o.Uint32(b[4*i:]))
+	}
+	return values
+}
+
+// EncodeFloat32s encodes the values into register values.
+func (o Order) EncodeFloat32s(values ...float32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		o.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+// DecodeUint64s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeUint64s(b []byte) []uint64 {
+	values := make([]uint64, len(b)/8)
+	for i := range values {
+		values[i] = o.Uint64(b[8*i:])
+	}
+	return values
+}
+
+// EncodeUint64s encodes the values into register values.
+func (o Order) EncodeUint64s(values ...uint64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		o.PutUint64(b[8*i:], v)
+	}
+	return b
+}
+
+// DecodeInt64s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeInt64s(b []byte) []int64 {
+	values := make([]int64, len(b)/8)
+	for i := range values {
+		values[i] = int64(o.Uint64(b[8*i:]))
+	}
+	return values
+}
+
+// EncodeInt64s encodes the values into register values.
+func (o Order) EncodeInt64s(values ...int64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		o.PutUint64(b[8*i:], uint64(v))
+	}
+	return b
+}
+
+// DecodeFloat64s decodes the register values b, any incomplete trailing value is ignored.
+func (o Order) DecodeFloat64s(b []byte) []float64 {
+	values := make([]float64, len(b)/8)
+	for i := range values {
+		values[i] = math.Float64frombits(o.Uint64(b[8*i:]))
+	}
+	return values
+}
+
+// EncodeFloat64s encodes the values into register values.
+func (o Order) EncodeFloat64s(values ...float64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		o.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return b
+}
+
+// order returns the byte order applying to requests issued with the given context.
+func (c *Client) order(ctx cancel.Context) Order {
+	if o, ok := value(ctx, orderKey).(Order); ok {
+		return o
+	}
+	return c.Order
+}
+
+// readHolding reads the holding registers of quantity values, each size bytes wide.
+func (c *Client) readHolding(ctx cancel.Context, uid byte, address, quantity uint16, size int) (values []byte, err error) {
+	if int(quantity)*size/2 > 125 {
+		return nil, IllegalDataValue
+	}
+	return c.ReadHoldingRegisters(ctx, uid, address, quantity*uint16(size/2))
+}
+
+// readInput reads the input registers of quantity values, each size bytes wide.
+func (c *Client) readInput(ctx cancel.Context, uid byte, address, quantity uint16, size int) (values []byte, err error) {
+	if int(quantity)*size/2 > 125 {
+		return nil, IllegalDataValue
+	}
+	return c.ReadInputRegisters(ctx, uid, address, quantity*uint16(size/2))
+}
+
+// ReadUint32s reads quantity unsigned 32 bit values from the holding registers starting at address.
+func (c *Client) ReadUint32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []uint32, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeUint32s(b), nil
+}
+
+// ReadInputUint32s reads quantity unsigned 32 bit values from the input registers starting at address.
+func (c *Client) ReadInputUint32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []uint32, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeUint32s(b), nil
+}
+
+// WriteUint32s writes the unsigned 32 bit values to the holding registers starting at address.
+func (c *Client) WriteUint32s(ctx cancel.Context, uid byte, address uint16, values ...uint32) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeUint32s(values...))
+}
+
+// ReadInt32s reads quantity signed 32 bit values from the holding registers starting at address.
+func (c *Client) ReadInt32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []int32, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeInt32s(b), nil
+}
+
+// ReadInputInt32s reads quantity signed 32 bit values from the input registers starting at address.
+func (c *Client) ReadInputInt32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []int32, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeInt32s(b), nil
+}
+
+// WriteInt32s writes the signed 32 bit values to the holding registers starting at address.
+func (c *Client) WriteInt32s(ctx cancel.Context, uid byte, address uint16, values ...int32) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeInt32s(values...))
+}
+
+// ReadFloat32s reads quantity 32 bit floating point values from the holding registers starting at address.
+func (c *Client) ReadFloat32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []float32, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeFloat32s(b), nil
+}
+
+// ReadInputFloat32s reads quantity 32 bit floating point values from the input registers starting at address.
+func (c *Client) ReadInputFloat32s(ctx cancel.Context, uid byte, address, quantity uint16) (values []float32, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 4)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeFloat32s(b), nil
+}
+
+// WriteFloat32s writes the 32 bit floating point values to the holding registers starting at address.
+func (c *Client) WriteFloat32s(ctx cancel.Context, uid byte, address uint16, values ...float32) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeFloat32s(values...))
+}
+
+// ReadUint64s reads quantity unsigned 64 bit values from the holding registers starting at address.
+func (c *Client) ReadUint64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []uint64, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeUint64s(b), nil
+}
+
+// ReadInputUint64s reads quantity unsigned 64 bit values from the input registers starting at address.
+func (c *Client) ReadInputUint64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []uint64, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeUint64s(b), nil
+}
+
+// WriteUint64s writes the unsigned 64 bit values to the holding registers starting at address.
+func (c *Client) WriteUint64s(ctx cancel.Context, uid byte, address uint16, values ...uint64) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeUint64s(values...))
+}
+
+// ReadInt64s reads quantity signed 64 bit values from the holding registers starting at address.
+func (c *Client) ReadInt64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []int64, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeInt64s(b), nil
+}
+
+// ReadInputInt64s reads quantity signed 64 bit values from the input registers starting at address.
+func (c *Client) ReadInputInt64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []int64, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeInt64s(b), nil
+}
+
+// WriteInt64s writes the signed 64 bit values to the holding registers starting at address.
+func (c *Client) WriteInt64s(ctx cancel.Context, uid byte, address uint16, values ...int64) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeInt64s(values...))
+}
+
+// ReadFloat64s reads quantity 64 bit floating point values from the holding registers starting at address.
+func (c *Client) ReadFloat64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []float64, err error) {
+	b, err := c.readHolding(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeFloat64s(b), nil
+}
+
+// ReadInputFloat64s reads quantity 64 bit floating point values from the input registers starting at address.
+func (c *Client) ReadInputFloat64s(ctx cancel.Context, uid byte, address, quantity uint16) (values []float64, err error) {
+	b, err := c.readInput(ctx, uid, address, quantity, 8)
+	if err != nil {
+		return nil, err
+	}
+	return c.order(ctx).DecodeFloat64s(b), nil
+}
+
+// WriteFloat64s writes the 64 bit floating point values to the holding registers starting at address.
+func (c *Client) WriteFloat64s(ctx cancel.Context, uid byte, address uint16, values ...float64) (err error) {
+	return c.WriteMultipleRegisters(ctx, uid, address, c.order(ctx).EncodeFloat64s(values...))
+}
+
+// BCD decodes the packed binary coded decimal spanning all of b, 4 digits per register.
+// ErrInvalidBCD is returned if any nibble exceeds 9.
+func (o Order) BCD(b []byte) (v uint64, err error) {
+	if len(b) > 8 {
+		return 0, ErrDataSizeExceeded
+	}
+	buf := append([]byte(nil), b...)
+	o.arrange(buf)
+	for _, x := range buf {
+		if x>>4 > 9 || x&0x0F > 9 {
+			return 0, ErrInvalidBCD
+		}
+		v = 100*v + 10*uint64(x>>4) + uint64(x&0x0F)
+	}
+	return v, nil
+}
+
+// PutBCD encodes v as packed binary coded decimal filling all of b, 4 digits per register.
+// ErrDataSizeExceeded is returned if v has more digits than fit into b.
+func (o Order) PutBCD(b []byte, v uint64) (err error) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(v%10) | byte(v/10%10)<<4
+		v /= 100
+	}
+	if v != 0 {
+		return ErrDataSizeExceeded
+	}
+	o.arrange(b)
+	return nil
+}
+
+// DecodeString decodes the ASCII string packed into the register values b, two characters per register.
+// If swap is set the characters of each register are swapped, as used by some devices.
+// Trailing pad and NUL characters are removed.
+func DecodeString(b []byte, swap bool, pad byte) string {
+	buf := append([]byte(nil), b...)
+	if swap {
+		BADC.arrange(buf)
+	}
+	n := len(buf)
+	for n > 0 && (buf[n-1] == pad || buf[n-1] == 0x00) {
+		n--
+	}
+	return string(buf[:n])
+}
+
+// EncodeString packs s into quantity registers, two characters per register.
+// The remaining space is filled with pad, if swap is set the characters of each register are swapped.
+// ErrDataSizeExceeded is returned if s doesn´t fit into the registers.
+func EncodeString(s string, quantity uint16, swap bool, pad byte) (values []byte, err error) {
+	if len(s) > 2*int(quantity) {
+		return nil, ErrDataSizeExceeded
+	}
+	values = make([]byte, 2*int(quantity))
+	for i := copy(values, s); i < len(values); i++ {
+		values[i] = pad
+	}
+	if swap {
+		BADC.arrange(values)
+	}
+	return values, nil
+}
+
+// Bitfield selects Width bits of a register, starting at bit Offset counted from the least significant one.
+type Bitfield struct {
+	Offset, Width uint
+}
+
+// mask returns the bits of the register covered by the field.
+func (f Bitfield) mask() uint16 {
+	return uint16((1<<f.Width - 1) << f.Offset)
+}
+
+// valid reports whether the field lies within a register.
+func (f Bitfield) valid() bool {
+	return f.Width > 0 && f.Offset+f.Width <= 16
+}
+
+// Get extracts the value of the field from the register.
+func (f Bitfield) Get(register uint16) uint16 {
+	return register & f.mask() >> f.Offset
+}
+
+// Set returns the register with the field replaced by value, excess bits of value are discarded.
+func (f Bitfield) Set(register, value uint16) uint16 {
+	return register&^f.mask() | value<<f.Offset&f.mask()
+}
+
+// Bitfields are the named fields of a register, such as a status word.
+type Bitfields map[string]Bitfield
+
+// Decode extracts the value of every field from the register.
+func (b Bitfields) Decode(register uint16) (values map[string]uint16) {
+	values = make(map[string]uint16, len(b))
+	for name, f := range b {
+		values[name] = f.Get(register)
+	}
+	return values
+}
+
+// Encode sets the given fields of the register, other bits are left unchanged.
+// The returned mask holds the bits of all given fields.
+// ErrInvalidParameter is returned for unknown or malformed fields and values exceeding their field.
+func (b Bitfields) Encode(register uint16, values map[string]uint16) (_, mask uint16, err error) {
+	for name, v := range values {
+		f, ok := b[name]
+		if !ok || !f.valid() || v > f.mask()>>f.Offset {
+			return 0, 0, ErrInvalidParameter
+		}
+		register, mask = f.Set(register, v), mask|f.mask()
+	}
+	return register, mask, nil
+}
+
+// ReadBCD reads the packed binary coded decimal spanning quantity (1 to 4) holding registers starting at address.
+func (c *Client) ReadBCD(ctx cancel.Context, uid byte, address, quantity uint16) (value uint64, err error) {
+	if quantity > 4 {
+		return 0, IllegalDataValue
+	}
+	b, err := c.ReadHoldingRegisters(ctx, uid, address, quantity)
+	if err != nil {
+		return 0, err
+	}
+	return c.order(ctx).BCD(b)
+}
+
+// ReadInputBCD reads the packed binary coded decimal spanning quantity (1 to 4) input registers starting at address.
+func (c *Client) ReadInputBCD(ctx cancel.Context, uid byte, address, quantity uint16) (value uint64, err error) {
+	if quantity > 4 {
+		return 0, IllegalDataValue
+	}
+	b, err := c.ReadInputRegisters(ctx, uid, address, quantity)
+	if err != nil {
+		return 0, err
+	}
+	return c.order(ctx).BCD(b)
+}
+
+// WriteBCD writes the value as packed binary coded decimal to quantity (1 to 4) holding registers starting at address.
+func (c *Client) WriteBCD(ctx cancel.Context, uid byte, address, quantity uint16, value uint64) (err error) {
+	if quantity < 1 || quantity > 4 {
+		return IllegalDataValue
+	}
+	b := make([]byte, 2*quantity)
+	if err := c.order(ctx).PutBCD(b, value); err != nil {
+		return err
+	}
+	return c.WriteMultipleRegisters(ctx, uid, address, b)
+}
+
+// ReadString reads the ASCII string packed into quantity holding registers starting at address.
+// See DecodeString for the meaning of swap and pad.
+func (c *Client) ReadString(ctx cancel.Context, uid byte, address, quantity uint16, swap bool, pad byte) (s string, err error) {
+	b, err := c.ReadHoldingRegisters(ctx, uid, address, quantity)
+	if err != nil {
+		return "", err
+	}
+	return DecodeString(b, swap, pad), nil
+}
+
+// ReadInputString reads the ASCII string packed into quantity input registers starting at address.
+// See DecodeString for the meaning of swap and pad.
+func (c *Client) ReadInputString(ctx cancel.Context, uid byte, address, quantity uint16, swap bool, pad byte) (s string, err error) {
+	b, err := c.ReadInputRegisters(ctx, uid, address, quantity)
+	if err != nil {
+		return "", err
+	}
+	return DecodeString(b, swap, pad), nil
+}
+
+// WriteString writes s packed into quantity holding registers starting at address.
+// See EncodeString for the meaning of swap and pad.
+func (c *Client) WriteString(ctx cancel.Context, uid byte, address, quantity uint16, s string, swap bool, pad byte) (err error) {
+	b, err := EncodeString(s, quantity, swap, pad)
+	if err != nil {
+		return err
+	}
+	return c.WriteMultipleRegisters(ctx, uid, address, b)
+}
+
+// ReadBitfields reads the holding register at address and returns the value of each of its fields.
+func (c *Client) ReadBitfields(ctx cancel.Context, uid byte, address uint16, fields Bitfields) (values map[string]uint16, err error) {
+	b, err := c.ReadHoldingRegisters(ctx, uid, address, 1)
+	if err != nil {
+		return nil, err
+	}
+	return fields.Decode(binary.BigEndian.Uint16(b)), nil
+}
+
+// ReadInputBitfields reads the input register at address and returns the value of each of its fields.
+func (c *Client) ReadInputBitfields(ctx cancel.Context, uid byte, address uint16, fields Bitfields) (values map[string]uint16, err error) {
+	b, err := c.ReadInputRegisters(ctx, uid, address, 1)
+	if err != nil {
+		return nil, err
+	}
+	return fields.Decode(binary.BigEndian.Uint16(b)), nil
+}
+
+// WriteBitfields sets the given fields of the holding register at address by a mask write register request.
+// The bits of any other field are left unchanged by the remote device.
+func (c *Client) WriteBitfields(ctx cancel.Context, uid byte, address uint16, fields Bitfields, values map[string]uint16) (err error) {
+	register, mask, err := fields.Encode(0, values)
+	if err != nil {
+		return err
+	}
+	return c.MaskWriteRegister(ctx, uid, address, ^mask, register)
+}
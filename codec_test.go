@@ -0,0 +1,124 @@
+package modbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	testCases := map[Order]struct{ u32, u64 []byte }{
+		ABCD: {
+			u32: []byte{0x01, 0x02, 0x03, 0x04},
+			u64: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		},
+		CDAB: {
+			u32: []byte{0x03, 0x04, 0x01, 0x02},
+			u64: []byte{0x07, 0x08, 0x05, 0x06, 0x03, 0x04, 0x01, 0x02},
+		},
+		BADC: {
+			u32: []byte{0x02, 0x01, 0x04, 0x03},
+			u64: []byte{0x02, 0x01, 0x04, 0x03, 0x06, 0x05, 0x08, 0x07},
+		},
+		DCBA: {
+			u32: []byte{0x04, 0x03, 0x02, 0x01},
+			u64: []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+		},
+	}
+	for o, tc := range testCases {
+		if b := o.EncodeUint32s(0x01020304); !bytes.Equal(b, tc.u32) {
+			t.Errorf("%v encoded uint32 invalid; want: %X; got: %X", o, tc.u32, b)
+		}
+		if v := o.Uint32(tc.u32); v != 0x01020304 {
+			t.Errorf("%v decoded uint32 invalid; want: %X; got: %X", o, 0x01020304, v)
+		}
+		if b := o.EncodeUint64s(0x0102030405060708); !bytes.Equal(b, tc.u64) {
+			t.Errorf("%v encoded uint64 invalid; want: %X; got: %X", o, tc.u64, b)
+		}
+		if v := o.Uint64(tc.u64); v != 0x0102030405060708 {
+			t.Errorf("%v decoded uint64 invalid; want: %X; got: %X", o, uint64(0x0102030405060708), v)
+		}
+	}
+}
+
+func TestOrderRoundTrip(t *testing.T) {
+	for _, o := range []Order{ABCD, CDAB, BADC, DCBA} {
+		if v := o.DecodeInt32s(o.EncodeInt32s(-1, -123456, 7)); len(v) != 3 || v[0] != -1 || v[1] != -123456 || v[2] != 7 {
+			t.Errorf("%v int32 round trip failed; got: %v", o, v)
+		}
+		if v := o.DecodeFloat32s(o.EncodeFloat32s(1.5, -273.15)); len(v) != 2 || v[0] != 1.5 || v[1] != -273.15 {
+			t.Errorf("%v float32 round trip failed; got: %v", o, v)
+		}
+		if v := o.DecodeInt64s(o.EncodeInt64s(-9876543210)); len(v) != 1 || v[0] != -9876543210 {
+			t.Errorf("%v int64 round trip failed; got: %v", o, v)
+		}
+		if v := o.DecodeFloat64s(o.EncodeFloat64s(3.141592653589793)); len(v) != 1 || v[0] != 3.141592653589793 {
+			t.Errorf("%v float64 round trip failed; got: %v", o, v)
+		}
+	}
+	// the well known representation of 123.456 as float32
+	if b := CDAB.EncodeFloat32s(123.456); !bytes.Equal(b, []byte{0xE9, 0x79, 0x42, 0xF6}) {
+		t.Errorf("CDAB float32 invalid; want: E97942F6; got: %X", b)
+	}
+	// incomplete trailing values are ignored
+	if v := ABCD.DecodeUint32s([]byte{0, 0, 0, 1, 0, 0}); len(v) != 1 || v[0] != 1 {
+		t.Errorf("decode of incomplete values invalid; got: %v", v)
+	}
+}
+
+func TestBCD(t *testing.T) {
+	b := make([]byte, 4)
+	if err := ABCD.PutBCD(b, 12345678); err != nil || !bytes.Equal(b, []byte{0x12, 0x34, 0x56, 0x78}) {
+		t.Fatalf("encoded BCD invalid; want: 12345678; got: %X, %v", b, err)
+	}
+	if err := CDAB.PutBCD(b, 1234); err != nil || !bytes.Equal(b, []byte{0x12, 0x34, 0x00, 0x00}) {
+		t.Fatalf("encoded word swapped BCD invalid; want: 12340000; got: %X, %v", b, err)
+	}
+	if v, err := CDAB.BCD(b); err != nil || v != 1234 {
+		t.Fatalf("decoded word swapped BCD invalid; want: 1234; got: %v, %v", v, err)
+	}
+	if err := ABCD.PutBCD(b[:2], 12345); err != ErrDataSizeExceeded {
+		t.Fatalf("encoding of oversized BCD returned unexpected error; want: %v; got: %v", ErrDataSizeExceeded, err)
+	}
+	if _, err := ABCD.BCD([]byte{0x12, 0x3A}); err != ErrInvalidBCD {
+		t.Fatalf("decoding of invalid BCD returned unexpected error; want: %v; got: %v", ErrInvalidBCD, err)
+	}
+}
+
+func TestString(t *testing.T) {
+	b, err := EncodeString("SN12345", 4, true, ' ')
+	if err != nil || !bytes.Equal(b, []byte("NS2143 5")) {
+		t.Fatalf("encoded string invalid; want: %q; got: %q, %v", "NS2143 5", b, err)
+	}
+	if s := DecodeString(b, true, ' '); s != "SN12345" {
+		t.Fatalf("decoded string invalid; want: %q; got: %q", "SN12345", s)
+	}
+	// NUL termination is always trimmed
+	if s := DecodeString([]byte("abc\x00\x00\x00"), false, ' '); s != "abc" {
+		t.Fatalf("decoded string invalid; want: %q; got: %q", "abc", s)
+	}
+	if _, err := EncodeString("too long", 3, false, 0); err != ErrDataSizeExceeded {
+		t.Fatalf("encoding of oversized string returned unexpected error; want: %v; got: %v", ErrDataSizeExceeded, err)
+	}
+}
+
+func TestBitfields(t *testing.T) {
+	fields := Bitfields{
+		"running": {Offset: 0, Width: 1},
+		"mode":    {Offset: 4, Width: 3},
+		"code":    {Offset: 8, Width: 8},
+	}
+	values := fields.Decode(0xA551)
+	if values["running"] != 1 || values["mode"] != 5 || values["code"] != 0xA5 {
+		t.Fatalf("decoded bitfields invalid; got: %v", values)
+	}
+	register, mask, err := fields.Encode(0xA551, map[string]uint16{"mode": 2, "running": 0})
+	if err != nil || register != 0xA520 || mask != 0x0071 {
+		t.Fatalf("encoded bitfields invalid; want: A520, 0071; got: %04X, %04X, %v", register, mask, err)
+	}
+	if _, _, err := fields.Encode(0, map[string]uint16{"mode": 8}); err != ErrInvalidParameter {
+		t.Fatalf("encoding of oversized value returned unexpected error; want: %v; got: %v", ErrInvalidParameter, err)
+	}
+	if _, _, err := fields.Encode(0, map[string]uint16{"unknown": 0}); err != ErrInvalidParameter {
+		t.Fatalf("encoding of unknown field returned unexpected error; want: %v; got: %v", ErrInvalidParameter, err)
+	}
+}
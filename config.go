@@ -1,7 +1,11 @@
 package modbus
 
 import (
+	"context"
+	"crypto/tls"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/GoAethereal/cancel"
 )
@@ -11,30 +15,49 @@ type Config struct {
 	// Mode defines the communication framing
 	// valid modes are:
 	//	- tcp
-	//	- rtu	(ToDo)
-	//	- ascii	(ToDo)
+	//	- rtu
+	//	- ascii
+	// Any mode can be combined with any kind, e.g. rtu over tcp as offered by serial to ethernet converters.
 	Mode string
 	// Kind specifies the underlying network layer
 	// valid kinds are:
 	//	- tcp
-	//	- udp 		(ToDo)
-	//	- serial	(ToDo)
+	//	- udp
+	//	- serial
+	//	- tls	(modbus/TCP security, usually on port 802)
 	Kind string
-	// Endpoint used for connecting to (client) or listening on (server)
+	// Endpoint used for connecting to (client) or listening on (server).
+	// For the serial kind this is the path of the device, e.g. /dev/ttyUSB0
 	Endpoint string
+	// Serial defines the line settings of the serial kind
+	Serial Serial
+	// TLS defines the certificates and verification of the tls kind.
+	// Clients are always required to authenticate by a certificate, as mandated by the modbus/TCP security specification.
+	// ClientAuth merely selects whether the certificate is verified (by default) or left to VerifyPeerCertificate.
+	TLS *tls.Config
+	// Turnaround is the delay a client waits after a broadcast request (unit id 0 in rtu or ascii mode),
+	// giving the devices time to process it. Defaults to 100ms.
+	// In rtu or ascii mode it´s also awaited after a timed out request, dropping a late response.
+	Turnaround time.Duration
 }
 
 // Verify validates the modbus.Options, thereby checking for invalid parameter.
 // If the options are valid no error (nil) is returned.
 func (cfg *Config) Verify() error {
 	switch cfg.Mode {
-	case "tcp" /*, "rtu", "ascii"*/ :
+	case "tcp", "rtu", "ascii":
 	default:
 		return ErrInvalidParameter
 	}
 
+	if cfg.Turnaround < 0 {
+		return ErrInvalidParameter
+	}
+
 	switch cfg.Kind {
-	case "tcp" /*, "udp", "serial"*/ :
+	case "tcp", "udp", "tls":
+	case "serial":
+		return cfg.Serial.verify()
 	default:
 		return ErrInvalidParameter
 	}
@@ -42,16 +65,36 @@ func (cfg *Config) Verify() error {
 	return nil
 }
 
+// broadcast reports whether a request to the given unit is a broadcast, which is never answered.
+// Broadcasts are only known to the serial line framings, in tcp mode the unit id 0 addresses the server itself.
+func (cfg Config) broadcast(uid byte) bool {
+	return uid == 0 && (cfg.Mode == "rtu" || cfg.Mode == "ascii")
+}
+
+// turnaround returns the delay after a broadcast or timed out request.
+func (cfg Config) turnaround() time.Duration {
+	if cfg.Turnaround > 0 {
+		return cfg.Turnaround
+	}
+	return 100 * time.Millisecond
+}
+
 // framer creates a new modbus framer from the given configuration.
 func (cfg Config) framer(_ cancel.Context) (framer, error) {
 	switch cfg.Mode {
 	case "tcp":
 		return &tcp{}, nil
+	case "rtu":
+		return &rtu{}, nil
+	case "ascii":
+		return &ascii{}, nil
 	}
 	return nil, ErrInvalidParameter
 }
 
-func (cfg Config) connection(ctx cancel.Context) (connection, error) {
+// connection establishes a new connection to the configured endpoint.
+// Inbound data is split into application data units by the given framer.
+func (cfg Config) connection(ctx cancel.Context, f framer) (connection, error) {
 	switch cfg.Kind {
 	case "tcp":
 		ctx, cancel := cancel.Promote(ctx)
@@ -60,7 +103,34 @@ func (cfg Config) connection(ctx cancel.Context) (connection, error) {
 		if err != nil {
 			return nil, err
 		}
-		return (&network{con: con, buf: make([]byte, 260)}).init()
+		return (&network{con: con, buf: f.buffer(), split: f.split}).init()
+	case "tls":
+		ctx, cancel := cancel.Promote(ctx)
+		defer cancel()
+		con, err := (&tls.Dialer{Config: cfg.tlsConfig()}).DialContext(ctx, "tcp", cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return (&network{con: con, buf: f.buffer(), split: f.split}).init()
+	case "udp":
+		ctx, cancel := cancel.Promote(ctx)
+		defer cancel()
+		con, err := new(net.Dialer).DialContext(ctx, cfg.Kind, cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return (&network{con: con, buf: f.buffer(), split: f.split, datagram: true}).init()
+	case "serial":
+		con, err := cfg.Serial.open(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		n := &network{con: con, buf: f.buffer(), split: f.split}
+		// only rtu frames are delimited by the silence on the line
+		if cfg.Mode == "rtu" {
+			n.silence = cfg.Serial.silence()
+		}
+		return n.init()
 	}
 	return nil, ErrInvalidParameter
 }
@@ -68,7 +138,8 @@ func (cfg Config) connection(ctx cancel.Context) (connection, error) {
 // listen creates a new listener on the configured endpoint.
 // If successful a acceptor function will be returned.
 // The function will block until a new connection is established or an error occurs.
-func (cfg Config) listen(ctx cancel.Context) (fn func() (connection, error), err error) {
+// The listener is closed once ctx is canceled, wg is done thereafter.
+func (cfg Config) listen(ctx cancel.Context, f framer, wg *sync.WaitGroup) (fn func() (connection, error), err error) {
 	switch cfg.Kind {
 	case "tcp":
 		l, err := net.Listen(cfg.Kind, cfg.Endpoint)
@@ -76,7 +147,9 @@ func (cfg Config) listen(ctx cancel.Context) (fn func() (connection, error), err
 			return nil, err
 		}
 		// start the watch-dog which will stop the listener when the context is canceled
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			<-ctx.Done()
 			l.Close()
 		}()
@@ -85,9 +158,97 @@ func (cfg Config) listen(ctx cancel.Context) (fn func() (connection, error), err
 			if err != nil {
 				return nil, err
 			}
-			return (&network{con: con, buf: make([]byte, 256)}).init()
+			return (&network{con: con, buf: f.buffer(), split: f.split}).init()
 		}
-
+	case "tls":
+		l, err := tls.Listen("tcp", cfg.Endpoint, cfg.tlsConfig())
+		if err != nil {
+			return nil, err
+		}
+		// start the watch-dog which will stop the listener when the context is canceled
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ctx.Done()
+			l.Close()
+		}()
+		// the handshakes are performed in the background, so a slow client can´t stall the listener
+		accept := make(chan connection)
+		go func() {
+			for {
+				con, err := l.Accept()
+				if err != nil {
+					return
+				}
+				go func(con *tls.Conn) {
+					c, err := handshake(con, f)
+					if err != nil {
+						con.Close()
+						return
+					}
+					select {
+					case accept <- c:
+					case <-ctx.Done():
+						c.close()
+					}
+				}(con.(*tls.Conn))
+			}
+		}()
+		fn = func() (connection, error) {
+			select {
+			case con := <-accept:
+				return con, nil
+			case <-ctx.Done():
+				return nil, context.Canceled
+			}
+		}
+	case "udp":
+		l, err := net.ListenPacket(cfg.Kind, cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		// start the watch-dog which will stop the listener when the context is canceled
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ctx.Done()
+			l.Close()
+		}()
+		d := &datagram{con: l, buf: f.buffer(), split: f.split, peers: make(map[string]*packet), accept: make(chan connection)}
+		go d.serve(ctx)
+		fn = d.next
+	case "serial":
+		con, err := cfg.connection(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		first := true
+		fn = func() (_ connection, err error) {
+			if first {
+				first = false
+				return con, nil
+			}
+			// the serial line only carries a single connection,
+			// a new one is opened once the previous got closed
+			if con != nil {
+				<-con.rx(ctx, func(_ []byte, err error) (quit bool) { return err != nil })
+			}
+			select {
+			case <-ctx.Done():
+				return nil, context.Canceled
+			default:
+			}
+			if con, err = cfg.connection(ctx, f); err != nil {
+				// slow down retries while the device is unavailable
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+			return con, err
+		}
+	default:
+		return nil, ErrInvalidParameter
 	}
 	return fn, nil
 }
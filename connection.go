@@ -2,7 +2,10 @@ package modbus
 
 import (
 	"container/list"
+	"errors"
+	"io"
 	"net"
+	"os"
 	"sync"
 	"time"
 
@@ -22,12 +25,39 @@ type connection interface {
 	rx(ctx cancel.Context, callback func(adu []byte, err error) (quit bool)) (done <-chan struct{})
 }
 
+// stream is the underlying transport of a network connection.
+// It´s satisfied by net.Conn as well as a non-blocking *os.File.
+type stream interface {
+	io.ReadWriteCloser
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+var (
+	_ stream = (net.Conn)(nil)
+	_ stream = (*os.File)(nil)
+)
+
+var (
+	_ connection = (*network)(nil)
+	_ connection = (*packet)(nil)
+)
+
 type network struct {
-	mtx sync.Mutex
-	ctx cancel.Signal
-	con net.Conn
-	buf []byte
-	l   list.List
+	receivers
+	// wmtx serializes the writes, the receivers are guarded by their own mutex
+	wmtx  sync.Mutex
+	ctx   cancel.Signal
+	con   stream
+	buf   []byte
+	split func(data []byte, end bool) (advance int, adu []byte, err error)
+	// silence is the time without transmission marking the end of a frame.
+	// If zero, frames are solely delimited by the framer.
+	silence time.Duration
+	// datagram signals that each read carries a whole packet,
+	// therefore its end is a frame boundary and remaining data is discarded.
+	datagram bool
+	once     sync.Once
 }
 
 func (c *network) ready() bool {
@@ -41,54 +71,105 @@ func (c *network) ready() bool {
 
 func (c *network) close() {
 	c.ctx.Cancel()
+	// a stream which was never read is closed right away
+	c.once.Do(func() {
+		c.con.Close()
+		c.broadcast(nil, net.ErrClosed)
+	})
 }
 
 func (c *network) init() (connection, error) {
-	go func() {
-		c.con.SetReadDeadline(time.Time{})
-		var wg sync.WaitGroup
-		wg.Add(1)
-		defer wg.Wait()
-		defer c.ctx.Cancel()
-		go func() {
-			defer wg.Done()
-			<-c.ctx.Done()
-			c.con.SetReadDeadline(time.Unix(1, 0))
-		}()
-		var (
-			n   int
-			err error
-		)
-		for err == nil {
-			n, err = c.con.Read(c.buf)
-			c.broadcast(c.buf[:n], err)
-		}
-	}()
 	return c, nil
 }
 
-func (c *network) broadcast(adu []byte, err error) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
-	var n *list.Element
-	for e := c.l.Front(); e != nil; e = n {
-		n = e.Next()
-		r := e.Value.(receiver)
-		if r.callback(adu, err) {
-			c.l.Remove(e)
-			close(r.done)
+func (c *network) rx(ctx cancel.Context, callback func(adu []byte, err error) (quit bool)) <-chan struct{} {
+	done := c.receivers.rx(ctx, callback)
+	// the stream is read once the first receiver is attached, so no inbound data gets lost
+	c.once.Do(func() { go c.read() })
+	return done
+}
+
+// read splits the inbound data into adus and passes them on to the receivers,
+// until the connection is closed or fails.
+func (c *network) read() {
+	defer c.con.Close()
+	c.con.SetReadDeadline(time.Time{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Wait()
+	defer c.ctx.Cancel()
+	go func() {
+		defer wg.Done()
+		<-c.ctx.Done()
+		c.con.SetReadDeadline(time.Unix(1, 0))
+	}()
+	var (
+		n, m int
+		err  error
+	)
+	for err == nil {
+		m, err = c.con.Read(c.buf[n:])
+		end := c.datagram
+		if c.silence > 0 && errors.Is(err, os.ErrDeadlineExceeded) && c.ready() {
+			// the line went silent, which marks the end of the frame
+			end, err = true, nil
+		}
+		if n, err = c.scan(n+m, end, err); err != nil {
+			c.ctx.Cancel()
+			c.broadcast(nil, err)
+		}
+		if c.silence > 0 && (m > 0 || end) {
+			c.watch(end)
 		}
 	}
 }
 
-type receiver struct {
-	done     chan struct{}
-	callback func(adu []byte, err error) (quit bool)
+// watch arms the read deadline for detecting the inter-frame silence after received data.
+// After the end of a frame the deadline is cleared until the next data arrives.
+func (c *network) watch(end bool) {
+	var t time.Time
+	if !end {
+		t = time.Now().Add(c.silence)
+	}
+	c.con.SetReadDeadline(t)
+	// restore the cancellation deadline, which might have been overwritten
+	if !c.ready() {
+		c.con.SetReadDeadline(time.Unix(1, 0))
+	}
+}
+
+// scan splits the first n buffered bytes into adus and passes them on to the receivers.
+// The remaining incomplete data is moved to the front of the buffer and its length returned.
+func (c *network) scan(n int, end bool, err error) (int, error) {
+	var off int
+	for off < n {
+		advance, adu, e := c.split(c.buf[off:n], end)
+		// a malformed packet is simply dropped, whereas a stream can´t be resynchronized
+		if e != nil && c.datagram {
+			break
+		}
+		if e != nil {
+			return 0, e
+		}
+		if adu != nil {
+			c.broadcast(adu, nil)
+		}
+		if advance == 0 {
+			break
+		}
+		off += advance
+	}
+	n = copy(c.buf, c.buf[off:n])
+	// discard the data if the buffer is exhausted without a complete adu
+	if n == len(c.buf) || c.datagram {
+		n = 0
+	}
+	return n, err
 }
 
 func (c *network) tx(ctx cancel.Context, adu []byte) (err error) {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	c.wmtx.Lock()
+	defer c.wmtx.Unlock()
 	var wg sync.WaitGroup
 	c.con.SetWriteDeadline(time.Time{})
 	done := make(chan struct{})
@@ -107,19 +188,55 @@ func (c *network) tx(ctx cancel.Context, adu []byte) (err error) {
 	return err
 }
 
-func (c *network) rx(ctx cancel.Context, callback func(adu []byte, err error) (quit bool)) (done <-chan struct{}) {
+// receivers manages the callbacks attached to a connection.
+type receivers struct {
+	mtx sync.Mutex
+	l   list.List
+	// err holds the reason the connection terminated
+	err error
+}
+
+func (c *receivers) broadcast(adu []byte, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err != nil {
+		c.err = err
+	}
+	var n *list.Element
+	for e := c.l.Front(); e != nil; e = n {
+		n = e.Next()
+		r := e.Value.(receiver)
+		if r.callback(adu, err) {
+			c.l.Remove(e)
+			close(r.done)
+		}
+	}
+}
+
+type receiver struct {
+	done     chan struct{}
+	callback func(adu []byte, err error) (quit bool)
+}
+
+func (c *receivers) rx(ctx cancel.Context, callback func(adu []byte, err error) (quit bool)) <-chan struct{} {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 	r := receiver{done: make(chan struct{}), callback: callback}
+	// a terminated connection immediately reports its cause
+	if c.err != nil {
+		callback(nil, c.err)
+		close(r.done)
+		return r.done
+	}
 	e := c.l.PushFront(r)
 	go func() {
 		select {
-		case <-done:
+		case <-r.done:
 		case <-ctx.Done():
 			c.mtx.Lock()
 			defer c.mtx.Unlock()
 			select {
-			case <-done:
+			case <-r.done:
 			default:
 				c.l.Remove(e)
 				close(r.done)
@@ -128,3 +245,136 @@ func (c *network) rx(ctx cancel.Context, callback func(adu []byte, err error) (q
 	}()
 	return r.done
 }
+
+// packet is a virtual connection to a single peer of a shared packet listener.
+// Inbound packets are queued until the first receiver is attached.
+type packet struct {
+	receivers
+	ctx  cancel.Signal
+	con  net.PacketConn
+	addr net.Addr
+	in   chan []byte
+	once sync.Once
+}
+
+func (c *packet) ready() bool {
+	select {
+	case <-c.ctx.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *packet) close() {
+	c.ctx.Cancel()
+}
+
+func (c *packet) tx(_ cancel.Context, adu []byte) (err error) {
+	_, err = c.con.WriteTo(adu, c.addr)
+	return err
+}
+
+func (c *packet) rx(ctx cancel.Context, callback func(adu []byte, err error) (quit bool)) <-chan struct{} {
+	done := c.receivers.rx(ctx, callback)
+	c.once.Do(func() { go c.pump() })
+	return done
+}
+
+// pump forwards the queued packets to the receivers.
+// The connection is closed once the peer stayed idle for too long.
+func (c *packet) pump() {
+	idle := time.NewTimer(time.Minute)
+	defer idle.Stop()
+	for {
+		select {
+		case adu := <-c.in:
+			c.broadcast(adu, nil)
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(time.Minute)
+		case <-idle.C:
+			c.close()
+		case <-c.ctx.Done():
+			c.broadcast(nil, net.ErrClosed)
+			return
+		}
+	}
+}
+
+// datagram demultiplexes the packets received by a listener into virtual connections per peer.
+type datagram struct {
+	mtx    sync.Mutex
+	con    net.PacketConn
+	buf    []byte
+	split  func(data []byte, end bool) (advance int, adu []byte, err error)
+	peers  map[string]*packet
+	accept chan connection
+}
+
+// serve reads the inbound packets until the listener is closed.
+// Each packet is expected to carry exactly one adu, everything else is dropped.
+func (d *datagram) serve(ctx cancel.Context) {
+	defer close(d.accept)
+	defer func() {
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		for _, p := range d.peers {
+			p.close()
+		}
+	}()
+	for {
+		n, addr, err := d.con.ReadFrom(d.buf)
+		if err != nil {
+			return
+		}
+		_, adu, err := d.split(d.buf[:n], true)
+		if err != nil || adu == nil {
+			continue
+		}
+		p, ok := d.peer(addr)
+		if !ok {
+			select {
+			case d.accept <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case p.in <- append([]byte(nil), adu...):
+		default:
+			// the peer is congested, drop the packet
+		}
+	}
+}
+
+// peer returns the virtual connection of the given address.
+// If none exists a new one is created, which is reported by ok=false.
+func (d *datagram) peer(addr net.Addr) (p *packet, ok bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if p, ok = d.peers[addr.String()]; ok && p.ready() {
+		return p, true
+	}
+	p = &packet{con: d.con, addr: addr, in: make(chan []byte, 16)}
+	d.peers[addr.String()] = p
+	go func() {
+		<-p.ctx.Done()
+		d.mtx.Lock()
+		defer d.mtx.Unlock()
+		if d.peers[addr.String()] == p {
+			delete(d.peers, addr.String())
+		}
+	}()
+	return p, false
+}
+
+// next blocks until a packet of a new peer arrives and returns its connection.
+func (d *datagram) next() (connection, error) {
+	con, ok := <-d.accept
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return con, nil
+}
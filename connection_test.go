@@ -0,0 +1,126 @@
+package modbus
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// receive collects the adus received on con until n of them arrived or the timeout elapsed.
+func receive(con connection, n int, timeout time.Duration) (adus []string) {
+	ctx := cancel.New().Timeout(timeout)
+	<-con.rx(ctx, func(adu []byte, err error) (quit bool) {
+		if err != nil {
+			return true
+		}
+		adus = append(adus, string(adu))
+		return len(adus) == n
+	})
+	return adus
+}
+
+func TestNetworkSplitASCII(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	f := &ascii{}
+	con, _ := (&network{con: local, buf: f.buffer(), split: f.split}).init()
+	defer con.close()
+
+	// the interrupted frame ":0A8102" is superseded by the following start character
+	want := []string{":1103006B00037E\r\n", ":1103006B00037E\r\n", ":0103000A000DE5\r\n"}
+	go func() {
+		for _, chunk := range []string{"noise:1103", "006B00037E\r", "\n:0A8102:1103006B", "00037E\r\n:0103000A000DE5\r\n"} {
+			remote.Write([]byte(chunk))
+		}
+	}()
+
+	got := receive(con, len(want), time.Second)
+	if len(got) != len(want) {
+		t.Fatalf("ascii split returned wrong number of adus; want %q; got: %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ascii split returned invalid adu at index %v; want %q; got: %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNetworkSplitSilence(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	f := &rtu{}
+	con, _ := (&network{con: local, buf: f.buffer(), split: f.split, silence: 20 * time.Millisecond}).init()
+	defer con.close()
+
+	// the torn frame can´t be delimited by its content, only the pause separates it from the next one
+	want := []string{"\x11\x03\x00\x6B\x00", "\x11\x03\x00\x6B\x00\x03\x76\x87"}
+	go func() {
+		remote.Write([]byte(want[0]))
+		time.Sleep(100 * time.Millisecond)
+		remote.Write([]byte(want[1]))
+	}()
+
+	got := receive(con, len(want), time.Second)
+	if len(got) != len(want) {
+		t.Fatalf("rtu split returned wrong number of adus; want %q; got: %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rtu split returned invalid adu at index %v; want %q; got: %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNetworkSplitTCP(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	f := &tcp{}
+	con, _ := (&network{con: local, buf: f.buffer(), split: f.split}).init()
+	defer con.close()
+
+	want := []string{
+		"\x00\x01\x00\x00\x00\x06\x01\x03\x00\x00\x00\x01",
+		"\x00\x02\x00\x00\x00\x05\x01\x03\x02\x00\x2A",
+		"\x00\x03\x00\x00\x00\x03\x01\x83\x02",
+	}
+	go func() {
+		// the first two adus are coalesced, the last one is segmented within its header
+		for _, chunk := range []string{want[0] + want[1][:4], want[1][4:] + want[2][:3], want[2][3:]} {
+			remote.Write([]byte(chunk))
+		}
+	}()
+
+	got := receive(con, len(want), time.Second)
+	if len(got) != len(want) {
+		t.Fatalf("tcp split returned wrong number of adus; want %q; got: %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tcp split returned invalid adu at index %v; want %q; got: %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNetworkSplitTCPLength(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	f := &tcp{}
+	con, _ := (&network{con: local, buf: f.buffer(), split: f.split}).init()
+	defer con.close()
+
+	go remote.Write([]byte("\x00\x01\x00\x00\x01\x00\x01\x03"))
+
+	var err error
+	<-con.rx(cancel.New().Timeout(time.Second), func(_ []byte, e error) (quit bool) {
+		err = e
+		return true
+	})
+	if err != ErrInvalidLength {
+		t.Fatalf("tcp split accepted oversized length field; want %v; got: %v", ErrInvalidLength, err)
+	}
+	if con.ready() {
+		t.Fatalf("connection is still ready after receiving an oversized length field")
+	}
+}
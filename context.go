@@ -0,0 +1,70 @@
+package modbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// key identifies a value carried by a context.
+type key int
+
+const (
+	roleKey key = iota
+	timeoutKey
+	orderKey
+)
+
+var _ context.Context = (*valueCtx)(nil)
+
+// valueCtx attaches a value to a context.
+// It implements context.Context, hence contexts derived by the standard library (e.g. context.WithCancel)
+// keep carrying the value. A cancel.Signal propagating the context doesn´t.
+type valueCtx struct {
+	cancel.Context
+	key key
+	val interface{}
+}
+
+// withValue returns a context wrapping ctx, which carries the value v under the key k.
+func withValue(ctx cancel.Context, k key, v interface{}) context.Context {
+	return &valueCtx{Context: ctx, key: k, val: v}
+}
+
+// Deadline returns the deadline of the wrapped context, if it´s known.
+func (c *valueCtx) Deadline() (deadline time.Time, ok bool) {
+	if d, ok := c.Context.(interface{ Deadline() (time.Time, bool) }); ok {
+		return d.Deadline()
+	}
+	return time.Time{}, false
+}
+
+// Err returns context.Canceled once the wrapped context is done, unless it reports a more specific error.
+func (c *valueCtx) Err() error {
+	if e, ok := c.Context.(interface{ Err() error }); ok {
+		return e.Err()
+	}
+	select {
+	case <-c.Done():
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+// Value returns the value associated with k, looking it up in the wrapped context if the key differs.
+func (c *valueCtx) Value(k interface{}) interface{} {
+	if k == c.key {
+		return c.val
+	}
+	return value(c.Context, k)
+}
+
+// value returns the value associated with k by ctx, nil if there is none.
+func value(ctx cancel.Context, k interface{}) interface{} {
+	if v, ok := ctx.(interface{ Value(interface{}) interface{} }); ok {
+		return v.Value(k)
+	}
+	return nil
+}
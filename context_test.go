@@ -0,0 +1,51 @@
+package modbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+func TestContextValues(t *testing.T) {
+	c := &Client{Timeout: time.Second, Order: ABCD}
+
+	ctx, stop := context.WithCancel(WithOrder(WithTimeout(context.Background(), time.Minute), DCBA))
+	defer stop()
+	if d := c.timeout(ctx); d != time.Minute {
+		t.Fatalf("timeout of a wrapped context is invalid; want %v; got: %v", time.Minute, d)
+	}
+	if o := c.order(ctx); o != DCBA {
+		t.Fatalf("order of a wrapped context is invalid; want %v; got: %v", DCBA, o)
+	}
+
+	// the innermost setting is overridden by the outer one
+	if d := c.timeout(WithTimeout(ctx, 0)); d != 0 {
+		t.Fatalf("timeout of an overridden context is invalid; want 0; got: %v", d)
+	}
+	if d, o := c.timeout(cancel.New()), c.order(cancel.New()); d != time.Second || o != ABCD {
+		t.Fatalf("settings of a plain context don´t fall back to the client; got: %v, %v", d, o)
+	}
+
+	sig := cancel.New()
+	session, stop := context.WithTimeout(WithTimeout(withValue(sig, roleKey, "operator"), time.Minute), time.Hour)
+	defer stop()
+	if role, ok := Role(session); !ok || role != "operator" {
+		t.Fatalf("role of a wrapped context is invalid; want operator; got: %q, %v", role, ok)
+	}
+	if _, ok := Role(ctx); ok {
+		t.Fatalf("role reported for a context without a client")
+	}
+
+	// the wrapped signal still cancels the derived context
+	sig.Cancel()
+	select {
+	case <-session.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("derived context wasn´t canceled by the wrapped signal")
+	}
+	if err := session.Err(); err != context.Canceled {
+		t.Fatalf("derived context returned unexpected error; want %v; got: %v", context.Canceled, err)
+	}
+}
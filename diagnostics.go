@@ -0,0 +1,153 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+)
+
+// diagnostics holds the state maintained by the server for the function code 0x08 on a single connection.
+// All counters are 16 bit wide and wrap around, they are accessed atomically.
+type diagnostics struct {
+	// listen signals the listen only mode, in which no requests are executed nor answered
+	listen     uint32
+	register   uint32
+	bus        uint32
+	errors     uint32
+	exceptions uint32
+	messages   uint32
+	silent     uint32
+	nak        uint32
+	busy       uint32
+	overrun    uint32
+	// mtx guards the communication event logs of the individual units
+	mtx   sync.Mutex
+	units map[byte]*events
+}
+
+// unit returns the communication event log of the given unit, creating it on first use.
+func (d *diagnostics) unit(uid byte) *events {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.units == nil {
+		d.units = make(map[byte]*events)
+	}
+	e, ok := d.units[uid]
+	if !ok {
+		e = &events{}
+		d.units[uid] = e
+	}
+	return e
+}
+
+// each calls fn for the communication event log of every known unit.
+func (d *diagnostics) each(fn func(e *events)) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for _, e := range d.units {
+		fn(e)
+	}
+}
+
+// count increments the given counter.
+func (d *diagnostics) count(counter *uint32) {
+	atomic.AddUint32(counter, 1)
+}
+
+// load returns the 16 bit value of the given counter.
+func (d *diagnostics) load(counter *uint32) uint16 {
+	return uint16(atomic.LoadUint32(counter))
+}
+
+// listening reports whether the listen only mode is active.
+func (d *diagnostics) listening() bool {
+	return atomic.LoadUint32(&d.listen) == 1
+}
+
+// ignores reports whether the request is dropped due to the listen only mode.
+// Only the restart communications option is processed while listening.
+func (d *diagnostics) ignores(code byte, req []byte) bool {
+	if !d.listening() {
+		return false
+	}
+	return code != 0x08 || len(req) != 4 || binary.BigEndian.Uint16(req) != 0x01
+}
+
+// clear resets all counters, including the event counters, and the diagnostic register.
+func (d *diagnostics) clear() {
+	for _, counter := range []*uint32{&d.register, &d.bus, &d.errors, &d.exceptions, &d.messages, &d.silent, &d.nak, &d.busy, &d.overrun} {
+		atomic.StoreUint32(counter, 0)
+	}
+	d.each((*events).reset)
+}
+
+// diagnose executes the diagnostic sub-function of the request addressed to the given unit.
+// If no response must be sent reply is false.
+func (d *diagnostics) diagnose(uid byte, req []byte) (res []byte, ex Exception, reply bool) {
+	if len(req) < 2 {
+		return nil, IllegalDataValue, true
+	}
+	sub := binary.BigEndian.Uint16(req)
+	switch sub {
+	case 0x00:
+		// return query data echoes the request regardless of its length
+		return req, 0, true
+	case 0x01, 0x02, 0x04, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10, 0x11, 0x12, 0x14:
+	default:
+		// unsupported sub-functions are rejected before their data is validated
+		return nil, IllegalFunction, true
+	}
+	if len(req) != 4 {
+		return nil, IllegalDataValue, true
+	}
+	value := binary.BigEndian.Uint16(req[2:])
+	if sub == 0x01 {
+		if value != 0x0000 && value != 0xFF00 {
+			return nil, IllegalDataValue, true
+		}
+		// the response is sent before the restart, unless the listen only mode was active
+		reply = !d.listening()
+		atomic.StoreUint32(&d.listen, 0)
+		d.clear()
+		if value == 0xFF00 {
+			d.each((*events).clear)
+		}
+		d.unit(uid).add(0x00)
+		return req, 0, reply
+	}
+	if value != 0 {
+		return nil, IllegalDataValue, true
+	}
+	var counter *uint32
+	switch sub {
+	case 0x02:
+		counter = &d.register
+	case 0x04:
+		atomic.StoreUint32(&d.listen, 1)
+		d.unit(uid).add(0x04)
+		return nil, 0, false
+	case 0x0A:
+		d.clear()
+		return req, 0, true
+	case 0x0B:
+		counter = &d.bus
+	case 0x0C:
+		counter = &d.errors
+	case 0x0D:
+		counter = &d.exceptions
+	case 0x0E:
+		counter = &d.messages
+	case 0x0F:
+		counter = &d.silent
+	case 0x10:
+		counter = &d.nak
+	case 0x11:
+		counter = &d.busy
+	case 0x12:
+		counter = &d.overrun
+	case 0x14:
+		atomic.StoreUint32(&d.overrun, 0)
+		return req, 0, true
+	}
+	return put(4, sub, d.load(counter)), 0, true
+}
@@ -0,0 +1,26 @@
+package modbus
+
+import "testing"
+
+func TestDiagnose(t *testing.T) {
+	testCases := []struct {
+		req []byte
+		ex  Exception
+	}{
+		{[]byte{0x00, 0x00, 0x12, 0x34, 0x56}, 0},
+		{[]byte{0x00, 0x0A, 0x00, 0x00}, 0},
+		{[]byte{0x00, 0x0A, 0x00, 0x01}, IllegalDataValue},
+		{[]byte{0x00, 0x0B, 0x00}, IllegalDataValue},
+		{[]byte{0x00, 0x01, 0x12, 0x34}, IllegalDataValue},
+		// change ascii input delimiter is not supported, regardless of its value
+		{[]byte{0x00, 0x03, 0x0A, 0x00}, IllegalFunction},
+		{[]byte{0x00, 0x03}, IllegalFunction},
+		{[]byte{0x00, 0x13, 0x00, 0x00}, IllegalFunction},
+		{[]byte{0x00}, IllegalDataValue},
+	}
+	for _, tc := range testCases {
+		if _, ex, _ := (&diagnostics{}).diagnose(1, tc.req); ex != tc.ex {
+			t.Fatalf("diagnose of % X returned unexpected exception; want %v; got: %v", tc.req, tc.ex, ex)
+		}
+	}
+}
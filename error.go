@@ -1,6 +1,10 @@
 package modbus
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// ErrMismatchedTransactionId indicates that a received modbus server response did not match
@@ -19,9 +23,37 @@ var (
 	// ErrMismatchedUnitId signals a mismatch of the unit identifier field.
 	// A normal response is expected to this value copied from the request.
 	ErrMismatchedUnitId = errors.New("modbus: mismatch of unit id")
+	// ErrMismatchedFunctionCode signals a response carrying another function code than the request.
+	// On serial lines this is a late response to a previous request, which is discarded by the client
+	// while it keeps waiting for the actual response.
+	ErrMismatchedFunctionCode = errors.New("modbus: mismatch of function code")
 	// ErrDataSizeExceeded indicates that the given data length exceeds the limits of a modbus
 	// package payload.
 	ErrDataSizeExceeded = errors.New("modbus: data size exceeds limit")
+	// ErrInvalidLength signals that the length field of a received frame is out of bounds.
+	// As the frame boundaries of the stream are lost, the connection is closed.
+	ErrInvalidLength = errors.New("modbus: invalid length field")
+	// ErrInvalidChecksum indicates that the checksum of a received frame did not match its content.
+	// The frame was most likely corrupted during transmission and has to be discarded.
+	ErrInvalidChecksum = errors.New("modbus: invalid checksum")
 	// ErrInvalidParameter signals a malformed input.
 	ErrInvalidParameter = errors.New("modbus: given parameter violates restriction")
+	// ErrInvalidBCD signals a binary coded decimal containing a nibble greater than 9.
+	ErrInvalidBCD = errors.New("modbus: invalid BCD digit")
 )
+
+// TimeoutError is returned if the remote device didn´t respond within the response timeout of the client.
+type TimeoutError struct {
+	// Duration is the response timeout which elapsed.
+	Duration time.Duration
+}
+
+// Error returns a human readable string representing the timeout.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("modbus: no response within %v", e.Duration)
+}
+
+// Timeout reports that the error is a timeout, as known from net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
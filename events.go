@@ -0,0 +1,117 @@
+package modbus
+
+import (
+	"sync"
+)
+
+// events is the communication event log of a single unit, as maintained by the server
+// for the function codes 0x0B and 0x0C.
+type events struct {
+	mtx sync.Mutex
+	// pending is the number of requests currently processed
+	pending int
+	// counter is incremented for each successfully completed request
+	counter uint16
+	// log holds up to 64 events, the most recent first
+	log []byte
+}
+
+// add stores the event at the front of the log, discarding the oldest one if full.
+func (e *events) add(event byte) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if len(e.log) < 64 {
+		e.log = append(e.log, 0)
+	}
+	copy(e.log[1:], e.log)
+	e.log[0] = event
+}
+
+// receive records the reception of a request.
+func (e *events) receive(uid byte, listening bool) {
+	event := byte(0x80)
+	if listening {
+		event |= 0x20
+	}
+	if uid == 0 {
+		event |= 0x40
+	}
+	e.mtx.Lock()
+	e.pending++
+	e.mtx.Unlock()
+	e.add(event)
+}
+
+// send records the completion of a request, reply signals whether a response was sent.
+// Only successfully executed requests, other than the retrieval of the event counter or log, are counted.
+// Requests dropped in listen only mode are not executed.
+func (e *events) send(code byte, ex Exception, executed, reply, listening bool) {
+	e.mtx.Lock()
+	e.pending--
+	if executed && ex == 0 && code != 0x0B && code != 0x0C {
+		e.counter++
+	}
+	e.mtx.Unlock()
+	if !reply {
+		return
+	}
+	event := byte(0x40)
+	if listening {
+		event |= 0x20
+	}
+	switch {
+	case ex == 0:
+	case ex <= IllegalDataValue:
+		event |= 0x01
+	case ex == SlaveDeviceFailure:
+		event |= 0x02
+	case ex == Acknowledge || ex == SlaveDeviceBusy:
+		event |= 0x04
+	case ex == 0x07:
+		event |= 0x08
+	}
+	e.add(event)
+}
+
+// reset clears the event counter.
+func (e *events) reset() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.counter = 0
+}
+
+// clear removes all events from the log.
+func (e *events) clear() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.log = e.log[:0]
+}
+
+// status returns 0xFFFF if a previous request is still processed, otherwise 0x0000.
+// Must be called with the lock held.
+func (e *events) status() uint16 {
+	if e.pending > 1 {
+		return 0xFFFF
+	}
+	return 0x0000
+}
+
+// commEventCounter answers the function code 0x0B.
+func (e *events) commEventCounter(req []byte) (res []byte, ex Exception) {
+	if len(req) != 0 {
+		return nil, IllegalDataAddress
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return put(4, e.status(), e.counter), 0
+}
+
+// commEventLog answers the function code 0x0C, messages is the bus message count.
+func (e *events) commEventLog(req []byte, messages uint16) (res []byte, ex Exception) {
+	if len(req) != 0 {
+		return nil, IllegalDataAddress
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return put(7+len(e.log), byte(6+len(e.log)), e.status(), e.counter, messages, e.log), 0
+}
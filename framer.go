@@ -1,7 +1,9 @@
 package modbus
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"sync/atomic"
 )
@@ -13,6 +15,10 @@ type framer interface {
 	decode(adu []byte) (uid, code byte, data []byte, err error)
 	verify(req, res []byte) (err error)
 	reply(uid, code byte, data, req []byte) (res []byte, err error)
+	// split extracts the first complete adu from the received data.
+	// end reports that the transport observed a boundary behind the data, like the end of a read.
+	// If more data is required no adu is returned, advance still allows to skip invalid data.
+	split(data []byte, end bool) (advance int, adu []byte, err error)
 }
 
 var _ framer = (*tcp)(nil)
@@ -66,3 +72,257 @@ func (s *tcp) reply(uid, code byte, data, req []byte) (res []byte, err error) {
 	res[0], res[1] = req[0], req[1]
 	return res, nil
 }
+
+func (s *tcp) split(data []byte, _ bool) (advance int, adu []byte, err error) {
+	if len(data) < 6 {
+		return 0, nil, nil
+	}
+	// the length field counts the unit id and the pdu
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	switch {
+	case n < 2 || n > 254:
+		return 0, nil, ErrInvalidLength
+	case len(data) < 6+n:
+		return 0, nil, nil
+	}
+	return 6 + n, data[:6+n], nil
+}
+
+var _ framer = (*rtu)(nil)
+
+type rtu struct{}
+
+func (s *rtu) buffer() []byte {
+	return make([]byte, 256)
+}
+
+func (s *rtu) encode(uid, code byte, data []byte) (adu []byte, err error) {
+	if len(data) > 252 {
+		return nil, ErrDataSizeExceeded
+	}
+	adu = s.buffer()
+	adu[0], adu[1] = uid, code
+	n := 2 + copy(adu[2:], data)
+	binary.LittleEndian.PutUint16(adu[n:], crc(adu[:n]))
+	return adu[:n+2], nil
+}
+
+func (s *rtu) decode(adu []byte) (uid, code byte, data []byte, err error) {
+	switch {
+	case len(adu) < 4:
+		return 0, 0, nil, errors.New("modbus: invalid request")
+	case !valid(adu):
+		return 0, 0, nil, ErrInvalidChecksum
+	case adu[1] >= 0x80 && len(adu) != 5:
+		return 0, 0, nil, errors.New("modbus: invalid request")
+	case adu[1] >= 0x80:
+		return 0, 0, nil, Exception(adu[2])
+	}
+	return adu[0], adu[1], adu[2 : len(adu)-2], nil
+}
+
+func (s *rtu) verify(req, res []byte) error {
+	switch {
+	case len(res) < 4 || !valid(res):
+		return ErrInvalidChecksum
+	case req[0] != 0 && req[0] != res[0]:
+		return ErrMismatchedUnitId
+	case req[1] != res[1]&0x7F:
+		return ErrMismatchedFunctionCode
+	}
+	return nil
+}
+
+func (s *rtu) reply(uid, code byte, data, _ []byte) (res []byte, err error) {
+	return s.encode(uid, code, data)
+}
+
+func (s *rtu) split(data []byte, end bool) (advance int, adu []byte, err error) {
+	if len(data) >= 4 {
+		// frames are delimited by their length as derived from the function code
+		lengths, more := s.lengths(data), false
+		for _, n := range lengths {
+			switch {
+			case n > len(data):
+				more = true
+			case valid(data[:n]):
+				return n, data[:n], nil
+			}
+		}
+		// frames of unknown or variable length end at the first matching checksum
+		for n := 4; !more && n <= len(data) && n <= 256; n++ {
+			if valid(data[:n]) {
+				return n, data[:n], nil
+			}
+		}
+	}
+	// a boundary observed by the transport terminates the frame regardless
+	if end && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// lengths returns the possible lengths of a request or response frame, derived from its function code.
+// Nil is returned if the length is unknown or variable.
+func (s *rtu) lengths(data []byte) []int {
+	// count returns the length of a frame with a byte count at index i
+	count := func(i, n int) int {
+		if len(data) <= i {
+			return len(data) + 1
+		}
+		return n + int(data[i])
+	}
+	switch code := data[1]; {
+	case code >= 0x80:
+		return []int{5}
+	case code >= 0x01 && code <= 0x04:
+		return []int{8, count(2, 5)}
+	case code == 0x05 || code == 0x06:
+		return []int{8}
+	case code == 0x07:
+		return []int{4, 5}
+	case code == 0x0B:
+		return []int{4, 8}
+	case code == 0x0C || code == 0x11:
+		return []int{4, count(2, 5)}
+	case code == 0x0F || code == 0x10:
+		return []int{8, count(6, 9)}
+	case code == 0x14 || code == 0x15:
+		return []int{count(2, 5)}
+	case code == 0x16:
+		return []int{10}
+	case code == 0x17:
+		return []int{count(2, 5), count(10, 13)}
+	case code == 0x18:
+		return []int{6, 6 + int(binary.BigEndian.Uint16(data[2:]))}
+	}
+	return nil
+}
+
+// valid reports whether the rtu frame ends with the checksum of its content.
+func valid(adu []byte) bool {
+	return binary.LittleEndian.Uint16(adu[len(adu)-2:]) == crc(adu[:len(adu)-2])
+}
+
+// crc calculates the cyclical redundancy checksum (CRC-16/MODBUS) of the given bytes.
+// The result has to be appended to the rtu frame in little endian order.
+func crc(buf []byte) uint16 {
+	sum := uint16(0xFFFF)
+	for _, b := range buf {
+		sum ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if sum&1 == 1 {
+				sum = sum>>1 ^ 0xA001
+			} else {
+				sum >>= 1
+			}
+		}
+	}
+	return sum
+}
+
+var _ framer = (*ascii)(nil)
+
+type ascii struct{}
+
+func (s *ascii) buffer() []byte {
+	return make([]byte, 513)
+}
+
+func (s *ascii) encode(uid, code byte, data []byte) (adu []byte, err error) {
+	if len(data) > 252 {
+		return nil, ErrDataSizeExceeded
+	}
+	raw := make([]byte, 3+len(data))
+	raw[0], raw[1] = uid, code
+	n := 2 + copy(raw[2:], data)
+	raw[n] = lrc(raw[:n])
+	adu = s.buffer()
+	adu[0] = ':'
+	n = 1
+	for _, b := range raw {
+		adu[n], adu[n+1] = hexDigits[b>>4], hexDigits[b&0x0F]
+		n += 2
+	}
+	adu[n], adu[n+1] = '\r', '\n'
+	return adu[:n+2], nil
+}
+
+func (s *ascii) decode(adu []byte) (uid, code byte, data []byte, err error) {
+	raw, err := s.unpack(adu)
+	switch {
+	case err != nil:
+		return 0, 0, nil, err
+	case raw[1] >= 0x80 && len(raw) != 4:
+		return 0, 0, nil, errors.New("modbus: invalid request")
+	case raw[1] >= 0x80:
+		return 0, 0, nil, Exception(raw[2])
+	}
+	return raw[0], raw[1], raw[2 : len(raw)-1], nil
+}
+
+func (s *ascii) verify(req, res []byte) error {
+	ask, err := s.unpack(req)
+	if err != nil {
+		return err
+	}
+	raw, err := s.unpack(res)
+	switch {
+	case err != nil:
+		return err
+	case ask[0] != 0 && ask[0] != raw[0]:
+		return ErrMismatchedUnitId
+	case ask[1] != raw[1]&0x7F:
+		return ErrMismatchedFunctionCode
+	}
+	return nil
+}
+
+func (s *ascii) reply(uid, code byte, data, _ []byte) (res []byte, err error) {
+	return s.encode(uid, code, data)
+}
+
+func (s *ascii) split(data []byte, end bool) (advance int, adu []byte, err error) {
+	// everything in front of the start character is discarded
+	i := bytes.IndexByte(data, ':')
+	if i < 0 {
+		return len(data), nil, nil
+	}
+	j := bytes.Index(data[i:], []byte{'\r', '\n'})
+	if j < 0 {
+		return i, nil, nil
+	}
+	adu = data[i : i+j+2]
+	// a repeated start character restarts the frame
+	return i + j + 2, adu[bytes.LastIndexByte(adu, ':'):], nil
+}
+
+// unpack converts the hexadecimal representation of an ascii frame to its binary form.
+// The returned slice holds the address, function code, data and the validated checksum.
+func (s *ascii) unpack(adu []byte) (raw []byte, err error) {
+	n := len(adu)
+	if n < 9 || n%2 == 0 || adu[0] != ':' || adu[n-2] != '\r' || adu[n-1] != '\n' {
+		return nil, errors.New("modbus: invalid request")
+	}
+	raw = make([]byte, (n-3)/2)
+	if _, err := hex.Decode(raw, adu[1:n-2]); err != nil {
+		return nil, errors.New("modbus: invalid request")
+	}
+	if lrc(raw) != 0 {
+		return nil, ErrInvalidChecksum
+	}
+	return raw, nil
+}
+
+const hexDigits = "0123456789ABCDEF"
+
+// lrc calculates the longitudinal redundancy checksum of the given bytes.
+// Summing up a valid frame including its checksum results in zero.
+func lrc(buf []byte) byte {
+	var sum byte
+	for _, b := range buf {
+		sum += b
+	}
+	return -sum
+}
@@ -0,0 +1,160 @@
+package modbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRTUEncode(t *testing.T) {
+	testCases := []struct {
+		uid, code  byte
+		data, want []byte
+	}{
+		{0x11, 0x03, []byte{0x00, 0x6B, 0x00, 0x03}, []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03, 0x76, 0x87}},
+		{0x01, 0x06, []byte{0x00, 0x01, 0x00, 0x03}, []byte{0x01, 0x06, 0x00, 0x01, 0x00, 0x03, 0x98, 0x0B}},
+		{0x11, 0x10, []byte{0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0A, 0x01, 0x02}, []byte{0x11, 0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0A, 0x01, 0x02, 0xC6, 0xF0}},
+	}
+	for _, tc := range testCases {
+		adu, err := (&rtu{}).encode(tc.uid, tc.code, tc.data)
+		if err != nil {
+			t.Fatalf("rtu encode failed: %v", err)
+		}
+		if !bytes.Equal(adu, tc.want) {
+			t.Fatalf("rtu encode returned invalid frame; want % X; got: % X", tc.want, adu)
+		}
+	}
+	if _, err := (&rtu{}).encode(1, 0x10, make([]byte, 253)); err != ErrDataSizeExceeded {
+		t.Fatalf("rtu encode accepted oversized data; want %v; got: %v", ErrDataSizeExceeded, err)
+	}
+}
+
+func TestRTUDecode(t *testing.T) {
+	testCases := []struct {
+		adu       []byte
+		uid, code byte
+		data      []byte
+		err       error
+	}{
+		{[]byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40, 0x49, 0xAD}, 0x11, 0x03, []byte{0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40}, nil},
+		{[]byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x41, 0x49, 0xAD}, 0, 0, nil, ErrInvalidChecksum},
+		{[]byte{0x0A, 0x81, 0x02, 0xB0, 0x53}, 0, 0, nil, IllegalDataAddress},
+	}
+	for _, tc := range testCases {
+		uid, code, data, err := (&rtu{}).decode(tc.adu)
+		switch {
+		case err != tc.err:
+			t.Fatalf("rtu decode of % X returned unexpected error; want %v; got: %v", tc.adu, tc.err, err)
+		case uid != tc.uid || code != tc.code || !bytes.Equal(data, tc.data):
+			t.Fatalf("rtu decode of % X returned invalid payload; want %v %v % X; got: %v %v % X", tc.adu, tc.uid, tc.code, tc.data, uid, code, data)
+		}
+	}
+}
+
+func TestRTUVerify(t *testing.T) {
+	req := []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03, 0x76, 0x87}
+	testCases := []struct {
+		res []byte
+		err error
+	}{
+		{[]byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40, 0x49, 0xAD}, nil},
+		{[]byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40, 0x49, 0xAE}, ErrInvalidChecksum},
+		{[]byte{0x0A, 0x81, 0x02, 0xB0, 0x53}, ErrMismatchedUnitId},
+		{[]byte{0x11, 0x03}, ErrInvalidChecksum},
+		{[]byte{0x11, 0x83, 0x02, 0xC1, 0x34}, nil},
+		// late response to a previous read coils request
+		{[]byte{0x11, 0x01, 0x01, 0x05, 0x95, 0x4B}, ErrMismatchedFunctionCode},
+	}
+	for _, tc := range testCases {
+		if err := (&rtu{}).verify(req, tc.res); err != tc.err {
+			t.Fatalf("rtu verify of % X returned unexpected error; want %v; got: %v", tc.res, tc.err, err)
+		}
+	}
+}
+
+func TestASCIIEncode(t *testing.T) {
+	adu, err := (&ascii{}).encode(0x11, 0x03, []byte{0x00, 0x6B, 0x00, 0x03})
+	if err != nil {
+		t.Fatalf("ascii encode failed: %v", err)
+	}
+	if want := ":1103006B00037E\r\n"; string(adu) != want {
+		t.Fatalf("ascii encode returned invalid frame; want %q; got: %q", want, adu)
+	}
+}
+
+func TestASCIIDecode(t *testing.T) {
+	testCases := []struct {
+		adu       string
+		uid, code byte
+		data      []byte
+		err       error
+	}{
+		{":1103006B00037E\r\n", 0x11, 0x03, []byte{0x00, 0x6B, 0x00, 0x03}, nil},
+		{":1103006b00037e\r\n", 0x11, 0x03, []byte{0x00, 0x6B, 0x00, 0x03}, nil},
+		{":1103006B00037F\r\n", 0, 0, nil, ErrInvalidChecksum},
+		{":0A810273\r\n", 0, 0, nil, IllegalDataAddress},
+	}
+	for _, tc := range testCases {
+		uid, code, data, err := (&ascii{}).decode([]byte(tc.adu))
+		switch {
+		case err != tc.err:
+			t.Fatalf("ascii decode of %q returned unexpected error; want %v; got: %v", tc.adu, tc.err, err)
+		case uid != tc.uid || code != tc.code || !bytes.Equal(data, tc.data):
+			t.Fatalf("ascii decode of %q returned invalid payload; want %v %v % X; got: %v %v % X", tc.adu, tc.uid, tc.code, tc.data, uid, code, data)
+		}
+	}
+	for _, adu := range []string{":1103\r\n", "1103006B00037E\r\n", ":1103006B00037E\n\n", ":1103006X00037E\r\n"} {
+		if _, _, _, err := (&ascii{}).decode([]byte(adu)); err == nil {
+			t.Fatalf("ascii decode accepted malformed frame %q", adu)
+		}
+	}
+}
+
+func TestASCIIVerify(t *testing.T) {
+	req := []byte(":1103006B00037E\r\n")
+	testCases := []struct {
+		res string
+		err error
+	}{
+		{":110302002AC0\r\n", nil},
+		{":1183026A\r\n", nil},
+		{":0A810273\r\n", ErrMismatchedUnitId},
+		// late response to a previous read coils request
+		{":11010105E8\r\n", ErrMismatchedFunctionCode},
+	}
+	for _, tc := range testCases {
+		if err := (&ascii{}).verify(req, []byte(tc.res)); err != tc.err {
+			t.Fatalf("ascii verify of %q returned unexpected error; want %v; got: %v", tc.res, tc.err, err)
+		}
+	}
+}
+
+func TestRTUSplit(t *testing.T) {
+	req := []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03, 0x76, 0x87}
+	res := []byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40, 0x49, 0xAD}
+	write := []byte{0x11, 0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0A, 0x01, 0x02, 0xC6, 0xF0}
+	custom := []byte{0x01, 0x41, 0xAA, 0xBB, 0x6F, 0x1F}
+	testCases := []struct {
+		data []byte
+		end  bool
+		want []byte
+	}{
+		{append(append([]byte{}, req...), res...), false, req},
+		{append(append([]byte{}, res...), req...), false, res},
+		{req[:6], false, nil},
+		{res[:7], false, nil},
+		{write[:6], false, nil},
+		{write, false, write},
+		{append(append([]byte{}, custom...), req...), false, custom},
+		{[]byte{0x01, 0x41, 0xAA}, false, nil},
+		{[]byte{0x01, 0x41, 0xAA}, true, []byte{0x01, 0x41, 0xAA}},
+	}
+	for _, tc := range testCases {
+		advance, adu, err := (&rtu{}).split(tc.data, tc.end)
+		switch {
+		case err != nil:
+			t.Fatalf("rtu split of % X failed: %v", tc.data, err)
+		case !bytes.Equal(adu, tc.want) || advance != len(tc.want):
+			t.Fatalf("rtu split of % X returned invalid adu; want % X; got: % X (advance %v)", tc.data, tc.want, adu, advance)
+		}
+	}
+}
@@ -13,6 +13,14 @@ type Handler interface {
 }
 
 var _ Handler = (*Mux)(nil)
+var _ authorizer = (*Mux)(nil)
+
+// authorizer is implemented by handlers authorizing requests apart from dispatching them.
+// The server authorizes each request before answering it, including the requests it answers itself.
+type authorizer interface {
+	authorize(ctx cancel.Context, uid, code byte) (ex Exception)
+	dispatch(ctx cancel.Context, uid, code byte, req []byte) (res []byte, ex Exception)
+}
 
 // Mux implements the modbus.Handler interface and is intended to be used as a server side request
 // multiplexer. When called by the server it will redirect the inbound message to the given function.
@@ -20,6 +28,12 @@ var _ Handler = (*Mux)(nil)
 // In case of an unknown function code the Fallback function, if set, will be executed.
 // All given functions must be safe for use by multiple go routines.
 type Mux struct {
+	// Authorize, if set, is called before each request is answered, including the ones answered
+	// by the server itself, such as diagnostics.
+	// Returning an exception rejects the request, as specified for modbus/TCP security
+	// an unauthorized request should be answered with modbus.IllegalFunction.
+	// The role of the client is available by modbus.Role(ctx).
+	Authorize                  func(ctx cancel.Context, uid, code byte) (ex Exception)
 	Fallback                   func(ctx cancel.Context, uid, code byte, req []byte) (res []byte, ex Exception)
 	ReadCoils                  func(ctx cancel.Context, uid byte, address, quantity uint16) (res []bool, ex Exception)
 	ReadDiscreteInputs         func(ctx cancel.Context, uid byte, address, quantity uint16) (res []bool, ex Exception)
@@ -27,14 +41,40 @@ type Mux struct {
 	ReadInputRegisters         func(ctx cancel.Context, uid byte, address, quantity uint16) (res []byte, ex Exception)
 	WriteSingleCoil            func(ctx cancel.Context, uid byte, address uint16, status bool) (ex Exception)
 	WriteSingleRegister        func(ctx cancel.Context, uid byte, address, value uint16) (ex Exception)
+	ReadExceptionStatus        func(ctx cancel.Context, uid byte) (status byte, ex Exception)
+	GetCommEventCounter        func(ctx cancel.Context, uid byte) (status, count uint16, ex Exception)
+	GetCommEventLog            func(ctx cancel.Context, uid byte) (status, count, messages uint16, events []byte, ex Exception)
 	WriteMultipleCoils         func(ctx cancel.Context, uid byte, address uint16, status []bool) (ex Exception)
 	WriteMultipleRegisters     func(ctx cancel.Context, uid byte, address uint16, values []byte) (ex Exception)
+	ReportServerID             func(ctx cancel.Context, uid byte) (id byte, running bool, data []byte, ex Exception)
+	ReadFileRecord             func(ctx cancel.Context, uid byte, records []FileRecord) (data [][]byte, ex Exception)
+	WriteFileRecord            func(ctx cancel.Context, uid byte, records []FileRecord) (ex Exception)
+	MaskWriteRegister          func(ctx cancel.Context, uid byte, address, andMask, orMask uint16) (ex Exception)
 	ReadWriteMultipleRegisters func(ctx cancel.Context, uid byte, rAddress, rQuantity, wAddress uint16, values []byte) (res []byte, ex Exception)
+	ReadFIFOQueue              func(ctx cancel.Context, uid byte, address uint16) (values []byte, ex Exception)
+	// EncapsulatedInterfaceTransport holds the handlers of the function code 0x2B per MEI type.
+	// The data of the request and response exclude the MEI type. Unregistered MEI types are passed to the Fallback,
+	// read device identification (MEI type 0x0E) is answered by the server if its Identification is set.
+	EncapsulatedInterfaceTransport map[byte]func(ctx cancel.Context, uid byte, data []byte) (res []byte, ex Exception)
 }
 
 // Handle dispatches incoming requests depending on their function code to the correlating callbacks
-// as defined inside the Mux.
+// as defined inside the Mux, once they are authorized.
 func (h *Mux) Handle(ctx cancel.Context, uid byte, code byte, req []byte) (res []byte, ex Exception) {
+	if ex := h.authorize(ctx, uid, code); ex != 0 {
+		return nil, ex
+	}
+	return h.dispatch(ctx, uid, code, req)
+}
+
+func (h *Mux) authorize(ctx cancel.Context, uid, code byte) (ex Exception) {
+	if h.Authorize == nil {
+		return 0
+	}
+	return h.Authorize(ctx, uid, code)
+}
+
+func (h *Mux) dispatch(ctx cancel.Context, uid, code byte, req []byte) (res []byte, ex Exception) {
 	switch code {
 	case 0x01:
 		return h.readCoils(ctx, uid, req)
@@ -48,12 +88,30 @@ func (h *Mux) Handle(ctx cancel.Context, uid byte, code byte, req []byte) (res [
 		return h.writeSingleCoil(ctx, uid, req)
 	case 0x06:
 		return h.writeSingleRegister(ctx, uid, req)
+	case 0x07:
+		return h.readExceptionStatus(ctx, uid, req)
+	case 0x0B:
+		return h.getCommEventCounter(ctx, uid, req)
+	case 0x0C:
+		return h.getCommEventLog(ctx, uid, req)
 	case 0x0F:
 		return h.writeMultipleCoils(ctx, uid, req)
 	case 0x10:
 		return h.writeMultipleRegisters(ctx, uid, req)
+	case 0x11:
+		return h.reportServerID(ctx, uid, req)
+	case 0x14:
+		return h.readFileRecord(ctx, uid, req)
+	case 0x15:
+		return h.writeFileRecord(ctx, uid, req)
+	case 0x16:
+		return h.maskWriteRegister(ctx, uid, req)
 	case 0x17:
 		return h.readWriteMultipleRegisters(ctx, uid, req)
+	case 0x18:
+		return h.readFIFOQueue(ctx, uid, req)
+	case 0x2B:
+		return h.encapsulatedInterfaceTransport(ctx, uid, req)
 	}
 	return h.fallback(ctx, uid, code, req)
 }
@@ -190,6 +248,51 @@ func (h *Mux) writeSingleRegister(ctx cancel.Context, uid byte, req []byte) (res
 	return req, 0
 }
 
+func (h *Mux) readExceptionStatus(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.ReadExceptionStatus == nil:
+		return nil, IllegalFunction
+	case len(req) != 0:
+		return nil, IllegalDataAddress
+	}
+	status, ex := h.ReadExceptionStatus(ctx, uid)
+	if ex != 0 {
+		return nil, ex
+	}
+	return []byte{status}, 0
+}
+
+func (h *Mux) getCommEventCounter(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.GetCommEventCounter == nil:
+		return nil, IllegalFunction
+	case len(req) != 0:
+		return nil, IllegalDataAddress
+	}
+	status, count, ex := h.GetCommEventCounter(ctx, uid)
+	if ex != 0 {
+		return nil, ex
+	}
+	return put(4, status, count), 0
+}
+
+func (h *Mux) getCommEventLog(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.GetCommEventLog == nil:
+		return nil, IllegalFunction
+	case len(req) != 0:
+		return nil, IllegalDataAddress
+	}
+	status, count, messages, events, ex := h.GetCommEventLog(ctx, uid)
+	switch {
+	case ex != 0:
+		return nil, ex
+	case len(events) > 64:
+		return nil, SlaveDeviceFailure
+	}
+	return put(7+len(events), byte(6+len(events)), status, count, messages, events), 0
+}
+
 func (h *Mux) writeMultipleCoils(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
 	switch {
 	case h.WriteMultipleCoils == nil:
@@ -232,6 +335,76 @@ func (h *Mux) writeMultipleRegisters(ctx cancel.Context, uid byte, req []byte) (
 	return req[:4], 0
 }
 
+func (h *Mux) reportServerID(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.ReportServerID == nil:
+		return nil, IllegalFunction
+	case len(req) != 0:
+		return nil, IllegalDataAddress
+	}
+	id, running, data, ex := h.ReportServerID(ctx, uid)
+	if ex != 0 {
+		return nil, ex
+	}
+	return (&ServerID{ID: id, Running: running, Data: data}).report(req)
+}
+
+func (h *Mux) readFileRecord(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	if h.ReadFileRecord == nil {
+		return nil, IllegalFunction
+	}
+	records, ex := fileRecords(req, false)
+	if ex != 0 {
+		return nil, ex
+	}
+	data, ex := h.ReadFileRecord(ctx, uid, records)
+	switch {
+	case ex != 0:
+		return nil, ex
+	case len(data) != len(records):
+		return nil, SlaveDeviceFailure
+	}
+	res = []byte{0}
+	for i, r := range records {
+		if len(data[i]) != 2*int(r.Length) {
+			return nil, SlaveDeviceFailure
+		}
+		res = append(res, put(2+len(data[i]), byte(1+len(data[i])), byte(0x06), data[i])...)
+	}
+	res[0] = byte(len(res) - 1)
+	return res, 0
+}
+
+func (h *Mux) writeFileRecord(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	if h.WriteFileRecord == nil {
+		return nil, IllegalFunction
+	}
+	records, ex := fileRecords(req, true)
+	if ex != 0 {
+		return nil, ex
+	}
+	if ex = h.WriteFileRecord(ctx, uid, records); ex != 0 {
+		return nil, ex
+	}
+	return req, 0
+}
+
+func (h *Mux) maskWriteRegister(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.MaskWriteRegister == nil:
+		return nil, IllegalFunction
+	case len(req) != 6:
+		return nil, IllegalDataAddress
+	}
+	address := binary.BigEndian.Uint16(req[0:])
+	andMask := binary.BigEndian.Uint16(req[2:])
+	orMask := binary.BigEndian.Uint16(req[4:])
+	if ex = h.MaskWriteRegister(ctx, uid, address, andMask, orMask); ex != 0 {
+		return nil, ex
+	}
+	return req, 0
+}
+
 func (h *Mux) readWriteMultipleRegisters(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
 	switch {
 	case h.ReadWriteMultipleRegisters == nil:
@@ -261,3 +434,37 @@ func (h *Mux) readWriteMultipleRegisters(ctx cancel.Context, uid byte, req []byt
 	}
 	return put(1+len(res), byte(len(res)), res), 0
 }
+
+func (h *Mux) readFIFOQueue(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	switch {
+	case h.ReadFIFOQueue == nil:
+		return nil, IllegalFunction
+	case len(req) != 2:
+		return nil, IllegalDataAddress
+	}
+	values, ex := h.ReadFIFOQueue(ctx, uid, binary.BigEndian.Uint16(req))
+	switch {
+	case ex != 0:
+		return nil, ex
+	case len(values)%2 != 0:
+		return nil, SlaveDeviceFailure
+	case fifoCheck(len(values)/2) != 0:
+		return nil, IllegalDataValue
+	}
+	count := uint16(len(values) / 2)
+	return put(4+len(values), 2+2*count, count, values), 0
+}
+
+func (h *Mux) encapsulatedInterfaceTransport(ctx cancel.Context, uid byte, req []byte) (res []byte, ex Exception) {
+	if len(req) < 1 {
+		return nil, IllegalDataAddress
+	}
+	fn, ok := h.EncapsulatedInterfaceTransport[req[0]]
+	if !ok || fn == nil {
+		return h.fallback(ctx, uid, 0x2B, req)
+	}
+	if res, ex = fn(ctx, uid, req[1:]); ex != 0 {
+		return nil, ex
+	}
+	return put(1+len(res), req[0], res), 0
+}
@@ -14,6 +14,22 @@ func boundCheck(address, quantity, limit uint16) Exception {
 	return 0
 }
 
+// reads reports whether the function code returns data, which is lost on a broadcast as it´s never answered.
+func reads(code byte) bool {
+	switch code {
+	case 0x01, 0x02, 0x03, 0x04, 0x07, 0x08, 0x0B, 0x0C, 0x11, 0x14, 0x17, 0x18, 0x2B:
+		return true
+	}
+	return false
+}
+
+func fifoCheck(count int) Exception {
+	if count > 31 {
+		return IllegalDataValue
+	}
+	return 0
+}
+
 func byteCount(bitCount uint16) int {
 	return int((bitCount + 7) / 8)
 }
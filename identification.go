@@ -0,0 +1,102 @@
+package modbus
+
+import "sort"
+
+// Access codes of the read device identification request (function code 0x2B / MEI type 0x0E).
+const (
+	// BasicDeviceIdentification streams the mandatory objects 0x00 to 0x02.
+	BasicDeviceIdentification byte = 0x01
+	// RegularDeviceIdentification streams the basic objects and the optional objects 0x03 to 0x7F.
+	RegularDeviceIdentification byte = 0x02
+	// ExtendedDeviceIdentification streams all objects, including the private ones from 0x80 to 0xFF.
+	ExtendedDeviceIdentification byte = 0x03
+	// IndividualDeviceIdentification reads a single object.
+	IndividualDeviceIdentification byte = 0x04
+)
+
+// Object ids of the device identification as defined by the specification.
+const (
+	ObjectVendorName          byte = 0x00
+	ObjectProductCode         byte = 0x01
+	ObjectMajorMinorRevision  byte = 0x02
+	ObjectVendorURL           byte = 0x03
+	ObjectProductName         byte = 0x04
+	ObjectModelName           byte = 0x05
+	ObjectUserApplicationName byte = 0x06
+)
+
+// DeviceIdentification holds the identification objects of a device, keyed by their object id.
+// The basic and regular objects are ASCII strings, the content of the extended ones is device dependent.
+type DeviceIdentification map[byte]string
+
+// lastObject returns the highest object id readable with the given stream access code.
+func lastObject(code byte) byte {
+	switch code {
+	case BasicDeviceIdentification:
+		return ObjectMajorMinorRevision
+	case RegularDeviceIdentification:
+		return 0x7F
+	}
+	return 0xFF
+}
+
+// conformity returns the conformity level of the objects, individual access is always supported.
+func (d DeviceIdentification) conformity() (level byte) {
+	level = 0x81
+	for id := range d {
+		switch {
+		case id >= 0x80:
+			return 0x83
+		case id > ObjectMajorMinorRevision:
+			level = 0x82
+		}
+	}
+	return level
+}
+
+// read answers the read device identification request, without the leading MEI type.
+// Objects which do not fit into a single response are announced by the more follows flag.
+func (d DeviceIdentification) read(req []byte) (res []byte, ex Exception) {
+	if len(req) != 2 {
+		return nil, IllegalDataAddress
+	}
+	code, id := req[0], req[1]
+	switch {
+	case code < BasicDeviceIdentification || code > IndividualDeviceIdentification:
+		return nil, IllegalDataValue
+	case code == IndividualDeviceIdentification:
+		value, ok := d[id]
+		if !ok {
+			return nil, IllegalDataAddress
+		}
+		return put(8+len(value), byte(0x0E), code, d.conformity(), byte(0x00), byte(0x00), byte(1), id, byte(len(value)), []byte(value)), 0
+	}
+	// an unknown start restarts the stream at the first object
+	if _, ok := d[id]; !ok || id > lastObject(code) {
+		id = 0
+	}
+	var ids []int
+	for k := range d {
+		if k >= id && k <= lastObject(code) {
+			ids = append(ids, int(k))
+		}
+	}
+	sort.Ints(ids)
+	res = put(6, byte(0x0E), code, d.conformity(), byte(0x00), byte(0x00), byte(0))
+	for _, k := range ids {
+		value := d[byte(k)]
+		if len(value) > 0xFF {
+			return nil, SlaveDeviceFailure
+		}
+		if len(res)+2+len(value) > 252 {
+			if res[5] == 0 {
+				return nil, SlaveDeviceFailure
+			}
+			res[3], res[4] = 0xFF, byte(k)
+			break
+		}
+		res = append(append(res, byte(k), byte(len(value))), value...)
+		res[5]++
+	}
+	return res, 0
+}
@@ -0,0 +1,697 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// ErrInvalidMapping signals a struct which can´t be mapped onto the data model of a device.
+// The returned errors wrap it, naming the offending field.
+var ErrInvalidMapping = errors.New("modbus: invalid register mapping")
+
+// table is one of the four primary tables of the modbus data model.
+type table byte
+
+const (
+	coils table = iota
+	discreteInputs
+	holdingRegisters
+	inputRegisters
+)
+
+// tables are the names of the tables as used in struct tags.
+var tables = map[string]table{
+	"co": coils,
+	"di": discreteInputs,
+	"hr": holdingRegisters,
+	"ir": inputRegisters,
+}
+
+// bits reports whether the table consists of single bits instead of registers.
+func (t table) bits() bool {
+	return t == coils || t == discreteInputs
+}
+
+// writable reports whether the table can be written by a client.
+func (t table) writable() bool {
+	return t == coils || t == holdingRegisters
+}
+
+// limit returns the maximum quantity of a single read (write=false) or write request.
+func (t table) limit(write bool) int {
+	switch {
+	case t.bits() && write:
+		return 1968
+	case t.bits():
+		return 2000
+	case write:
+		return 123
+	}
+	return 125
+}
+
+// orders are the names of the byte orders as used in struct tags, case insensitive.
+var orders = map[string]Order{
+	"ABCD": ABCD,
+	"CDAB": CDAB,
+	"BADC": BADC,
+	"DCBA": DCBA,
+}
+
+// kind is the encoding of a single value.
+type kind int
+
+const (
+	kindBool kind = iota
+	kindUint16
+	kindInt16
+	kindUint32
+	kindInt32
+	kindFloat32
+	kindUint64
+	kindInt64
+	kindFloat64
+	kindString
+	kindBCD
+	kindBits
+)
+
+// kinds are the names of the encodings as used in struct tags.
+var kinds = map[string]kind{
+	"bool":    kindBool,
+	"uint16":  kindUint16,
+	"int16":   kindInt16,
+	"uint32":  kindUint32,
+	"int32":   kindInt32,
+	"float32": kindFloat32,
+	"uint64":  kindUint64,
+	"int64":   kindInt64,
+	"float64": kindFloat64,
+	"string":  kindString,
+	"bcd":     kindBCD,
+}
+
+// natural returns the encoding of a Go type, if unambiguous.
+func natural(t reflect.Type) (k kind, ok bool) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return kindBool, true
+	case reflect.Uint16:
+		return kindUint16, true
+	case reflect.Int16:
+		return kindInt16, true
+	case reflect.Uint32:
+		return kindUint32, true
+	case reflect.Int32:
+		return kindInt32, true
+	case reflect.Float32:
+		return kindFloat32, true
+	case reflect.Uint64:
+		return kindUint64, true
+	case reflect.Int64:
+		return kindInt64, true
+	case reflect.Float64:
+		return kindFloat64, true
+	case reflect.String:
+		return kindString, true
+	}
+	return 0, false
+}
+
+// numeric reports whether values of the Go type can be converted from and to any numeric encoding.
+func numeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// width returns the number of registers, respectively bits, occupied by a value.
+func (k kind) width() int {
+	switch k {
+	case kindBool, kindUint16, kindInt16:
+		return 1
+	case kindUint32, kindInt32, kindFloat32:
+		return 2
+	}
+	return 4
+}
+
+// decode reads the value of the register encoding from b.
+func (k kind) decode(b []byte, o Order) reflect.Value {
+	switch k {
+	case kindUint16:
+		return reflect.ValueOf(o.Uint16(b))
+	case kindInt16:
+		return reflect.ValueOf(int16(o.Uint16(b)))
+	case kindUint32:
+		return reflect.ValueOf(o.Uint32(b))
+	case kindInt32:
+		return reflect.ValueOf(o.DecodeInt32s(b[:4])[0])
+	case kindFloat32:
+		return reflect.ValueOf(o.DecodeFloat32s(b[:4])[0])
+	case kindUint64:
+		return reflect.ValueOf(o.Uint64(b))
+	case kindInt64:
+		return reflect.ValueOf(o.DecodeInt64s(b[:8])[0])
+	}
+	return reflect.ValueOf(o.DecodeFloat64s(b[:8])[0])
+}
+
+// encode writes the value v in the register encoding into b.
+// The value is converted as by a Go conversion.
+func (k kind) encode(b []byte, v reflect.Value, o Order) {
+	switch k {
+	case kindUint16:
+		o.PutUint16(b, uint16(v.Convert(reflect.TypeOf(uint16(0))).Uint()))
+	case kindInt16:
+		o.PutUint16(b, uint16(v.Convert(reflect.TypeOf(int16(0))).Int()))
+	case kindUint32:
+		o.PutUint32(b, uint32(v.Convert(reflect.TypeOf(uint32(0))).Uint()))
+	case kindInt32:
+		copy(b, o.EncodeInt32s(int32(v.Convert(reflect.TypeOf(int32(0))).Int())))
+	case kindFloat32:
+		copy(b, o.EncodeFloat32s(float32(v.Convert(reflect.TypeOf(float32(0))).Float())))
+	case kindUint64:
+		o.PutUint64(b, v.Convert(reflect.TypeOf(uint64(0))).Uint())
+	case kindInt64:
+		copy(b, o.EncodeInt64s(v.Convert(reflect.TypeOf(int64(0))).Int()))
+	case kindFloat64:
+		copy(b, o.EncodeFloat64s(v.Convert(reflect.TypeOf(float64(0))).Float()))
+	}
+}
+
+// point is a struct field mapped onto a range of a table.
+type point struct {
+	index   []int
+	table   table
+	address uint16
+	kind    kind
+	// order overrides the order of the client if set
+	order *Order
+	// count is the number of elements of an array, 0 for single values
+	count    int
+	readonly bool
+	// length is the number of registers of a string or BCD value
+	length int
+	// swap and pad define the packing of a string
+	swap bool
+	pad  byte
+	// bits selects the part of the register holding the value of a bitfield
+	bits Bitfield
+}
+
+// width returns the number of registers, respectively bits, occupied by a single element.
+func (p *point) width() int {
+	switch p.kind {
+	case kindString, kindBCD:
+		return p.length
+	case kindBits:
+		return 1
+	}
+	return p.kind.width()
+}
+
+// quantity returns the number of registers, respectively bits, covered by the point.
+func (p *point) quantity() int {
+	if p.count > 0 {
+		return p.count * p.width()
+	}
+	return p.width()
+}
+
+// get decodes the element of type t from the register values b.
+func (p *point) get(b []byte, t reflect.Type, o Order) (reflect.Value, error) {
+	b = b[:2*p.width()]
+	switch p.kind {
+	case kindString:
+		return reflect.ValueOf(DecodeString(b, p.swap, p.pad)).Convert(t), nil
+	case kindBCD:
+		v, err := o.BCD(b)
+		return reflect.ValueOf(v).Convert(t), err
+	case kindBits:
+		v := p.bits.Get(binary.BigEndian.Uint16(b))
+		if t.Kind() == reflect.Bool {
+			return reflect.ValueOf(v != 0).Convert(t), nil
+		}
+		return reflect.ValueOf(v).Convert(t), nil
+	}
+	return p.kind.decode(b, o).Convert(t), nil
+}
+
+// put encodes the element v into the register values b.
+func (p *point) put(b []byte, v reflect.Value, o Order) error {
+	b = b[:2*p.width()]
+	switch p.kind {
+	case kindString:
+		values, err := EncodeString(v.String(), uint16(p.length), p.swap, p.pad)
+		if err != nil {
+			return err
+		}
+		copy(b, values)
+	case kindBCD:
+		if (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64) && v.Float() < 0 ||
+			v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 && v.Int() < 0 {
+			return ErrInvalidParameter
+		}
+		return o.PutBCD(b, v.Convert(reflect.TypeOf(uint64(0))).Uint())
+	case kindBits:
+		var x uint16
+		if v.Kind() == reflect.Bool {
+			if v.Bool() {
+				x = 1
+			}
+		} else {
+			x = uint16(v.Convert(reflect.TypeOf(uint16(0))).Uint())
+		}
+		binary.BigEndian.PutUint16(b, p.bits.Set(binary.BigEndian.Uint16(b), x))
+	default:
+		p.kind.encode(b, v, o)
+	}
+	return nil
+}
+
+// image holds the data of a contiguous range of a table.
+type image struct {
+	bits []bool
+	regs []byte
+}
+
+// decode sets the field v from the image, offset is the address of the point relative to the image.
+func (p *point) decode(v reflect.Value, img image, offset int, o Order) error {
+	if p.order != nil {
+		o = *p.order
+	}
+	set := func(v reflect.Value, i int) error {
+		if p.table.bits() {
+			v.SetBool(img.bits[offset+i])
+			return nil
+		}
+		x, err := p.get(img.regs[2*(offset+i*p.width()):], v.Type(), o)
+		if err != nil {
+			return err
+		}
+		v.Set(x)
+		return nil
+	}
+	if p.count == 0 {
+		return set(v, 0)
+	}
+	for i := 0; i < p.count; i++ {
+		if err := set(v.Index(i), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode writes the field v into the image, offset is the address of the point relative to the image.
+// Bitfields are merged into the present register values.
+func (p *point) encode(v reflect.Value, img image, offset int, o Order) error {
+	if p.order != nil {
+		o = *p.order
+	}
+	put := func(v reflect.Value, i int) error {
+		if p.table.bits() {
+			img.bits[offset+i] = v.Bool()
+			return nil
+		}
+		return p.put(img.regs[2*(offset+i*p.width()):], v, o)
+	}
+	if p.count == 0 {
+		return put(v, 0)
+	}
+	for i := 0; i < p.count; i++ {
+		if err := put(v.Index(i), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layout is the parsed mapping of a struct type.
+type layout []*point
+
+// layouts caches the layout per struct type.
+var layouts sync.Map
+
+// mapping returns the struct addressed by the pointer v together with its layout.
+func mapping(v interface{}) (reflect.Value, layout, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("%w: expected a non-nil pointer to a struct, got %T", ErrInvalidMapping, v)
+	}
+	rv = rv.Elem()
+	if l, ok := layouts.Load(rv.Type()); ok {
+		return rv, l.(layout), nil
+	}
+	l, err := parse(rv.Type(), nil)
+	if err == nil {
+		err = l.overlap(rv.Type())
+	}
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+	layouts.Store(rv.Type(), l)
+	return rv, l, nil
+}
+
+// overlap returns an error if two points of the layout share a coil or register,
+// except for bitfields occupying distinct bits of it. The struct type t is used to name the fields.
+func (l layout) overlap(t reflect.Type) error {
+	for i, p := range l {
+		for _, q := range l[:i] {
+			switch {
+			case p.table != q.table:
+			case int(p.address)+p.quantity() <= int(q.address) || int(q.address)+q.quantity() <= int(p.address):
+			case p.kind == kindBits && q.kind == kindBits && p.bits.mask()&q.bits.mask() == 0:
+			default:
+				return fmt.Errorf("%w: fields %s.%s and %s.%s overlap", ErrInvalidMapping,
+					t.Name(), t.FieldByIndex(q.index).Name, t.Name(), t.FieldByIndex(p.index).Name)
+			}
+		}
+	}
+	return nil
+}
+
+// parse collects the tagged fields of the struct type t, untagged struct fields are descended into.
+func parse(t reflect.Type, index []int) (l layout, err error) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		tag, ok := f.Tag.Lookup("modbus")
+		switch {
+		case f.PkgPath != "" || tag == "-":
+			continue
+		case !ok && f.Type.Kind() == reflect.Struct:
+			sub, err := parse(f.Type, idx)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, sub...)
+			continue
+		case !ok:
+			continue
+		}
+		p, err := parsePoint(f.Type, tag)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s.%s: %v", ErrInvalidMapping, t.Name(), f.Name, err)
+		}
+		p.index = idx
+		l = append(l, p)
+	}
+	return l, nil
+}
+
+// parsePoint parses the tag of a field of type t, which has the form:
+//
+//	table,address[,type][,order][,ro][,len=registers][,swap][,pad=byte][,bits=offset[:width]]
+//
+// The type defaults to the one of the field, the order to the one of the client.
+func parsePoint(t reflect.Type, tag string) (p *point, err error) {
+	opts := strings.Split(tag, ",")
+	if len(opts) < 2 {
+		return nil, errors.New("missing table or address")
+	}
+	p = &point{}
+	var ok bool
+	if p.table, ok = tables[strings.TrimSpace(opts[0])]; !ok {
+		return nil, fmt.Errorf("unknown table %q", opts[0])
+	}
+	address, err := strconv.ParseUint(strings.TrimSpace(opts[1]), 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q", opts[1])
+	}
+	p.address = uint16(address)
+	typed := false
+	for _, opt := range opts[2:] {
+		opt = strings.TrimSpace(opt)
+		if k, ok := kinds[opt]; ok {
+			p.kind, typed = k, true
+			continue
+		}
+		if o, ok := orders[strings.ToUpper(opt)]; ok {
+			p.order = &o
+			continue
+		}
+		key, value := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			key, value = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case "ro":
+			p.readonly = true
+		case "swap":
+			p.swap = true
+		case "len":
+			n, err := strconv.ParseUint(value, 0, 8)
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("invalid length %q", value)
+			}
+			p.length = int(n)
+		case "pad":
+			pad, err := strconv.ParseUint(value, 0, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid padding %q", value)
+			}
+			p.pad = byte(pad)
+		case "bits":
+			if p.bits, err = parseBits(value); err != nil {
+				return nil, err
+			}
+			p.kind, typed = kindBits, true
+		default:
+			return nil, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	if t.Kind() == reflect.Array {
+		if p.count = t.Len(); p.count == 0 {
+			return nil, errors.New("empty array")
+		}
+		t = t.Elem()
+	}
+	if !typed {
+		if p.kind, ok = natural(t); !ok || p.table.bits() != (p.kind == kindBool) {
+			return nil, fmt.Errorf("type %v requires an explicit encoding", t)
+		}
+	}
+	if p.kind == kindBCD && p.length == 0 {
+		p.length = 1
+	}
+	switch {
+	case p.table.bits() && (p.kind != kindBool || t.Kind() != reflect.Bool):
+		return nil, errors.New("coils and discrete inputs map to bool only")
+	case p.table.bits():
+	case p.kind == kindString && (t.Kind() != reflect.String || p.length == 0):
+		return nil, errors.New("strings require a string field and their length")
+	case p.kind == kindBCD && (!numeric(t) || p.length > 4):
+		return nil, errors.New("BCD values require a numeric field of up to 4 registers")
+	case p.kind == kindBits && !numeric(t) && t.Kind() != reflect.Bool:
+		return nil, fmt.Errorf("type %v can´t be stored in a bitfield", t)
+	case p.kind != kindString && p.kind != kindBCD && p.kind != kindBits && (p.kind == kindBool || !numeric(t)):
+		return nil, fmt.Errorf("type %v can´t be stored in registers", t)
+	}
+	switch {
+	case p.length > 0 && p.kind != kindString && p.kind != kindBCD:
+		return nil, errors.New("length only applies to strings and BCD values")
+	case (p.swap || p.pad != 0) && p.kind != kindString:
+		return nil, errors.New("swap and pad only apply to strings")
+	case int(p.address)+p.quantity() > 0x10000:
+		return nil, errors.New("exceeds the addressable range")
+	case p.quantity() > p.table.limit(p.table.writable() && !p.readonly):
+		return nil, errors.New("exceeds the quantity of a single request")
+	}
+	return p, nil
+}
+
+// parseBits parses a bitfield of the form offset[:width], the width defaults to a single bit.
+func parseBits(s string) (f Bitfield, err error) {
+	f.Width = 1
+	offset, width := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		offset, width = s[:i], s[i+1:]
+	}
+	o, err := strconv.ParseUint(offset, 10, 8)
+	if err != nil {
+		return f, fmt.Errorf("invalid bitfield %q", s)
+	}
+	f.Offset = uint(o)
+	if width != "" {
+		w, err := strconv.ParseUint(width, 10, 8)
+		if err != nil {
+			return f, fmt.Errorf("invalid bitfield %q", s)
+		}
+		f.Width = uint(w)
+	}
+	if !f.valid() {
+		return f, fmt.Errorf("bitfield %q exceeds the register", s)
+	}
+	return f, nil
+}
+
+// block is a contiguous range of a table, transferred by a single request.
+type block struct {
+	table    table
+	address  uint16
+	quantity int
+	points   []*point
+}
+
+// plan groups the points into as few blocks as possible.
+// A block spans gaps of up to the given number of unmapped addresses between the points, writes must not span any.
+func plan(points []*point, gap int, write bool) (blocks []block) {
+	sorted := append([]*point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].table != sorted[j].table {
+			return sorted[i].table < sorted[j].table
+		}
+		return sorted[i].address < sorted[j].address
+	})
+	for _, p := range sorted {
+		if n := len(blocks); n > 0 {
+			b := &blocks[n-1]
+			end := int(p.address) + p.quantity()
+			switch {
+			case b.table != p.table:
+			case int(p.address) > int(b.address)+b.quantity+gap:
+			case end-int(b.address) > p.table.limit(write):
+			default:
+				if end-int(b.address) > b.quantity {
+					b.quantity = end - int(b.address)
+				}
+				b.points = append(b.points, p)
+				continue
+			}
+		}
+		blocks = append(blocks, block{table: p.table, address: p.address, quantity: p.quantity(), points: []*point{p}})
+	}
+	return blocks
+}
+
+// read fetches the data of the block from the remote device.
+func (c *Client) read(ctx cancel.Context, uid byte, b block) (img image, err error) {
+	switch b.table {
+	case coils:
+		img.bits, err = c.ReadCoils(ctx, uid, b.address, uint16(b.quantity))
+	case discreteInputs:
+		img.bits, err = c.ReadDiscreteInputs(ctx, uid, b.address, uint16(b.quantity))
+	case holdingRegisters:
+		img.regs, err = c.ReadHoldingRegisters(ctx, uid, b.address, uint16(b.quantity))
+	case inputRegisters:
+		img.regs, err = c.ReadInputRegisters(ctx, uid, b.address, uint16(b.quantity))
+	}
+	return img, err
+}
+
+// Unmarshal reads the mapped fields of the struct pointed to by v from the remote device.
+// Fields are mapped by tags of the form `modbus:"table,address[,type][,order][,options]"` where:
+//
+//	table	is one of co (coils), di (discrete inputs), hr (holding registers) or ir (input registers)
+//	address	is the address of the first register or bit, decimal or 0x prefixed hex
+//	type	is one of bool, uint16, int16, uint32, int32, float32, uint64, int64, float64, string or bcd,
+//		by default it´s derived from the field, which is converted as by a Go conversion
+//	order	is one of abcd, cdab, badc or dcba, by default the order of the client applies
+//	ro	excludes the field from Marshal
+//	len=n	sets the number of registers of a string (required) or BCD value (1 to 4, default 1)
+//	swap	swaps the characters of each register of a string
+//	pad=b	sets the byte filling up a string, by default 0x00
+//	bits=o:w	maps the field onto w bits (default 1) at offset o of the register, see Bitfield
+//
+// Fixed size arrays map to consecutive values, untagged struct fields are descended into.
+// A field must fit into a single request, fields sharing a coil or register are invalid unless they are bitfields of distinct bits.
+// The fields are fetched by the fewest possible requests, which span unmapped addresses up to the MaxGap of the client.
+func (c *Client) Unmarshal(ctx cancel.Context, uid byte, v interface{}) (err error) {
+	rv, l, err := mapping(v)
+	if err != nil {
+		return err
+	}
+	o := c.order(ctx)
+	for _, b := range plan(l, c.MaxGap, false) {
+		img, err := c.read(ctx, uid, b)
+		if err != nil {
+			return err
+		}
+		for _, p := range b.points {
+			if err := p.decode(rv.FieldByIndex(p.index), img, int(p.address-b.address), o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal writes the mapped coils and holding registers of the struct pointed to by v to the remote device.
+// Discrete inputs, input registers and fields tagged read only (ro) are skipped.
+// See Unmarshal for the format of the tags. Only contiguous fields are combined into a single request,
+// unmapped addresses are never written. Bitfields are written by mask write register requests,
+// leaving the other bits of the register unchanged.
+func (c *Client) Marshal(ctx cancel.Context, uid byte, v interface{}) (err error) {
+	rv, l, err := mapping(v)
+	if err != nil {
+		return err
+	}
+	var points, bitfields []*point
+	for _, p := range l {
+		switch {
+		case !p.table.writable() || p.readonly:
+		case p.kind == kindBits:
+			bitfields = append(bitfields, p)
+		default:
+			points = append(points, p)
+		}
+	}
+	o := c.order(ctx)
+	for _, b := range plan(points, 0, true) {
+		img := image{bits: make([]bool, b.quantity), regs: make([]byte, 2*b.quantity)}
+		for _, p := range b.points {
+			if err := p.encode(rv.FieldByIndex(p.index), img, int(p.address-b.address), o); err != nil {
+				return err
+			}
+		}
+		if b.table == coils {
+			err = c.WriteMultipleCoils(ctx, uid, b.address, img.bits...)
+		} else {
+			err = c.WriteMultipleRegisters(ctx, uid, b.address, img.regs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// bitfields are merged into the registers by the remote device
+	var addresses []int
+	masks := make(map[uint16][2]uint16)
+	for _, p := range bitfields {
+		img := image{regs: make([]byte, 2*p.quantity())}
+		if err := p.encode(rv.FieldByIndex(p.index), img, 0, o); err != nil {
+			return err
+		}
+		for i := 0; i < p.quantity(); i++ {
+			address := p.address + uint16(i)
+			m, ok := masks[address]
+			if !ok {
+				addresses = append(addresses, int(address))
+			}
+			m[0], m[1] = m[0]|p.bits.mask(), m[1]|binary.BigEndian.Uint16(img.regs[2*i:])
+			masks[address] = m
+		}
+	}
+	sort.Ints(addresses)
+	for _, address := range addresses {
+		m := masks[uint16(address)]
+		if err := c.MaskWriteRegister(ctx, uid, uint16(address), ^m[0], m[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
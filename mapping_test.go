@@ -0,0 +1,142 @@
+package modbus
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	type device struct {
+		A uint16   `modbus:"hr,0"`
+		B float32  `modbus:"hr,10,cdab"`
+		C [2]int64 `modbus:"hr,100"`
+		D int      `modbus:"hr,200,int16"`
+		E bool     `modbus:"co,5"`
+		F [3]bool  `modbus:"co,6"`
+		G uint32   `modbus:"ir,0x10"`
+		H float64  `modbus:"hr,1,int32,ro"`
+		I struct {
+			J uint16 `modbus:"hr,3"`
+		}
+	}
+	_, l, err := mapping(&device{})
+	if err != nil {
+		t.Fatalf("mapping failed: %v", err)
+	}
+	contiguous := []block{
+		{table: coils, address: 5, quantity: 4},
+		{table: holdingRegisters, address: 0, quantity: 4},
+		{table: holdingRegisters, address: 10, quantity: 2},
+		{table: holdingRegisters, address: 100, quantity: 8},
+		{table: holdingRegisters, address: 200, quantity: 1},
+		{table: inputRegisters, address: 16, quantity: 2},
+	}
+	testCases := []struct {
+		name  string
+		gap   int
+		write bool
+		want  []block
+	}{
+		{"read", 0, false, contiguous},
+		{"write", 0, true, contiguous},
+		{"read spanning gaps", 10, false, []block{
+			{table: coils, address: 5, quantity: 4},
+			{table: holdingRegisters, address: 0, quantity: 12},
+			{table: holdingRegisters, address: 100, quantity: 8},
+			{table: holdingRegisters, address: 200, quantity: 1},
+			{table: inputRegisters, address: 16, quantity: 2},
+		}},
+	}
+	for _, tc := range testCases {
+		blocks := plan(l, tc.gap, tc.write)
+		if len(blocks) != len(tc.want) {
+			t.Fatalf("%v plan invalid; want %v blocks; got: %v", tc.name, len(tc.want), len(blocks))
+		}
+		for i, b := range tc.want {
+			if blocks[i].table != b.table || blocks[i].address != b.address || blocks[i].quantity != b.quantity {
+				t.Errorf("%v block %v invalid; want: %+v; got: %+v", tc.name, i, b, blocks[i])
+			}
+		}
+	}
+}
+
+func TestPlanLimit(t *testing.T) {
+	type device struct {
+		A [100]uint16 `modbus:"hr,0"`
+		B [100]uint16 `modbus:"hr,100"`
+	}
+	_, l, err := mapping(&device{})
+	if err != nil {
+		t.Fatalf("mapping failed: %v", err)
+	}
+	if blocks := plan(l, 0, false); len(blocks) != 2 {
+		t.Fatalf("plan exceeding the request limit invalid; want 2 blocks; got: %v", len(blocks))
+	}
+}
+
+func TestMappingErrors(t *testing.T) {
+	testCases := map[string]interface{}{
+		"no pointer": struct{}{},
+		"unknown table": &struct {
+			A uint16 `modbus:"xx,0"`
+		}{},
+		"missing address": &struct {
+			A uint16 `modbus:"hr"`
+		}{},
+		"invalid address": &struct {
+			A uint16 `modbus:"hr,70000"`
+		}{},
+		"unknown option": &struct {
+			A uint16 `modbus:"hr,0,foo"`
+		}{},
+		"ambiguous type": &struct {
+			A int `modbus:"hr,0"`
+		}{},
+		"bool register": &struct {
+			A bool `modbus:"hr,0"`
+		}{},
+		"numeric coil": &struct {
+			A uint16 `modbus:"co,0"`
+		}{},
+		"string register": &struct {
+			A string `modbus:"hr,0,uint16"`
+		}{},
+		"exceeds range": &struct {
+			A uint64 `modbus:"hr,65534"`
+		}{},
+		"exceeds limit": &struct {
+			A [126]uint16 `modbus:"hr,0"`
+		}{},
+		"exceeds write limit": &struct {
+			A [124]uint16 `modbus:"hr,0"`
+		}{},
+		"exceeds coil write limit": &struct {
+			A [1969]bool `modbus:"co,0"`
+		}{},
+		"overlapping points": &struct {
+			A uint32 `modbus:"hr,0"`
+			B uint16 `modbus:"hr,1"`
+		}{},
+		"overlapping bitfields": &struct {
+			A uint8 `modbus:"hr,0,bits=0:4"`
+			B uint8 `modbus:"hr,0,bits=2:4"`
+		}{},
+	}
+	for name, v := range testCases {
+		if _, _, err := mapping(v); !errors.Is(err, ErrInvalidMapping) {
+			t.Errorf("%v: want %v; got: %v", name, ErrInvalidMapping, err)
+		}
+	}
+
+	// read only points are limited by the read requests, distinct bits of a register and other tables don´t overlap
+	valid := &struct {
+		A [125]uint16 `modbus:"hr,0,ro"`
+		B [125]uint16 `modbus:"ir,0"`
+		C [2000]bool  `modbus:"co,0,ro"`
+		D uint8       `modbus:"hr,125,bits=0:4"`
+		E uint8       `modbus:"hr,125,bits=4:4"`
+	}{}
+	if _, _, err := mapping(valid); err != nil {
+		t.Errorf("valid mapping returned unexpected error: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package modbus_test
 
 import (
+	"bytes"
+	"errors"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -21,6 +24,20 @@ var (
 	c  = (&modbus.Client{Config: cfg})
 )
 
+// serve starts the server s in the background.
+// The returned function cancels ctx and waits until the server released its endpoint.
+func serve(ctx *cancel.Signal, s *modbus.Server, h modbus.Handler) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx, h)
+	}()
+	return func() {
+		ctx.Cancel()
+		<-done
+	}
+}
+
 func TestReadCoils(t *testing.T) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -247,6 +264,905 @@ func TestWriteSingleRegister(t *testing.T) {
 	}
 }
 
+func TestReadExceptionStatus(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	testCases := map[byte]byte{
+		1:   0x00,
+		2:   0x6D,
+		255: 0xFF,
+	}
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadExceptionStatus: func(_ cancel.Context, uid byte) (status byte, ex modbus.Exception) {
+			if status, ok := testCases[uid]; ok {
+				return status, 0
+			}
+			return 0, modbus.SlaveDeviceFailure
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	for uid, want := range testCases {
+		status, err := c.ReadExceptionStatus(ctx, uid)
+		if err != nil {
+			t.Fatalf("read exception status failed: %v", err)
+		}
+		if status != want {
+			t.Fatalf("read exception status received invalid value for unit %v; want %08b; got: %08b", uid, want, status)
+		}
+	}
+	if _, err := c.ReadExceptionStatus(ctx, 3); err != modbus.SlaveDeviceFailure {
+		t.Fatalf("read exception status returned unexpected error; want %v; got: %v", modbus.SlaveDeviceFailure, err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			if address != 0 {
+				return nil, modbus.IllegalDataAddress
+			}
+			return make([]byte, 2*quantity), 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	if err := c.ClearCounters(ctx, 1); err != nil {
+		t.Fatalf("clear counters failed: %v", err)
+	}
+	if err := c.ReturnQueryData(ctx, 1, []byte("hello there!")); err != nil {
+		t.Fatalf("return query data failed: %v", err)
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 1, 1); err != modbus.IllegalDataAddress {
+		t.Fatalf("read holding registers returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+
+	testCases := map[string]struct {
+		fn   func(ctx cancel.Context, uid byte) (uint16, error)
+		want uint16
+	}{
+		"diagnostic register":     {c.ReturnDiagnosticRegister, 0},
+		"bus message count":       {c.ReturnBusMessageCount, 4},
+		"bus exception count":     {c.ReturnBusExceptionErrorCount, 1},
+		"bus communication count": {c.ReturnBusCommunicationErrorCount, 0},
+		"slave message count":     {c.ReturnSlaveMessageCount, 7},
+		"slave no response count": {c.ReturnSlaveNoResponseCount, 0},
+	}
+	for _, name := range []string{"diagnostic register", "bus message count", "bus exception count", "bus communication count", "slave message count", "slave no response count"} {
+		tc := testCases[name]
+		got, err := tc.fn(ctx, 1)
+		if err != nil {
+			t.Fatalf("return %v failed: %v", name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("return %v received invalid value; want %v; got: %v", name, tc.want, got)
+		}
+	}
+
+	// in listen only mode the requests are neither executed nor answered
+	if err := c.ForceListenOnlyMode(ctx, 1); err != nil {
+		t.Fatalf("force listen only mode failed: %v", err)
+	}
+	// the request is unanswered, give the server time to process it
+	time.Sleep(100 * time.Millisecond)
+	if _, err := c.ReadHoldingRegisters(cancel.New().Timeout(250*time.Millisecond), 1, 0, 1); err == nil {
+		t.Fatalf("read holding registers succeeded in listen only mode")
+	}
+	if err := c.RestartCommunications(cancel.New().Timeout(250*time.Millisecond), 1, false); err == nil {
+		t.Fatalf("restart communications responded in listen only mode")
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("read holding registers failed after restart communications: %v", err)
+	}
+	if err := c.RestartCommunications(ctx, 1, true); err != nil {
+		t.Fatalf("restart communications failed: %v", err)
+	}
+}
+
+func TestCommEventLog(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		WriteSingleRegister: func(_ cancel.Context, _ byte, address, _ uint16) (ex modbus.Exception) {
+			if address != 0 {
+				return modbus.IllegalDataAddress
+			}
+			return 0
+		},
+		GetCommEventCounter: func(_ cancel.Context, uid byte) (status, count uint16, ex modbus.Exception) {
+			if uid != 6 {
+				return 0, 0, modbus.IllegalFunction
+			}
+			return 0xFFFF, 42, 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	if err := c.RestartCommunications(ctx, 1, true); err != nil {
+		t.Fatalf("restart communications failed: %v", err)
+	}
+
+	counter := func(uid byte, wantStatus, wantCount uint16) {
+		t.Helper()
+		status, count, err := c.GetCommEventCounter(ctx, uid)
+		if err != nil {
+			t.Fatalf("get comm event counter failed: %v", err)
+		}
+		if status != wantStatus || count != wantCount {
+			t.Fatalf("get comm event counter received invalid values; want %04X, %v; got: %04X, %v", wantStatus, wantCount, status, count)
+		}
+	}
+
+	counter(5, 0, 0)
+	if err := c.WriteSingleRegister(ctx, 5, 0, 1); err != nil {
+		t.Fatalf("write single register failed: %v", err)
+	}
+	if err := c.WriteSingleRegister(ctx, 5, 1, 1); err != modbus.IllegalDataAddress {
+		t.Fatalf("write single register returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+	// only the successful write is counted
+	counter(5, 0, 1)
+	// the handler takes precedence over the log of the server
+	counter(6, 0xFFFF, 42)
+
+	status, count, messages, events, err := c.GetCommEventLog(ctx, 5)
+	if err != nil {
+		t.Fatalf("get comm event log failed: %v", err)
+	}
+	if status != 0 || count != 1 || messages != 6 {
+		t.Fatalf("get comm event log received invalid values; want 0000, 1, 6; got: %04X, %v, %v", status, count, messages)
+	}
+	if want := []byte{0x80, 0x40, 0x80, 0x41, 0x80, 0x40, 0x80, 0x40, 0x80}; !bytes.Equal(events, want) {
+		t.Fatalf("get comm event log received invalid events; want % X; got: % X", want, events)
+	}
+}
+
+func TestReportServerID(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	cfg := modbus.Config{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1342"}
+	s, c := &modbus.Server{Config: cfg, Identity: &modbus.ServerID{ID: 0x2A, Running: true, Data: []byte("hello there!")}}, &modbus.Client{Config: cfg}
+	defer serve(ctx, s, &modbus.Mux{
+		ReportServerID: func(_ cancel.Context, uid byte) (id byte, running bool, data []byte, ex modbus.Exception) {
+			if uid != 2 {
+				return 0, false, nil, modbus.IllegalFunction
+			}
+			return 0x07, false, nil, 0
+		},
+	})()
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	id, running, data, err := c.ReportServerID(ctx, 1)
+	if err != nil {
+		t.Fatalf("report server id failed: %v", err)
+	}
+	if id != 0x2A || !running || string(data) != "hello there!" {
+		t.Fatalf("report server id received invalid identity; got: %v, %v, %q", id, running, data)
+	}
+	// the handler takes precedence over the identity of the server
+	id, running, data, err = c.ReportServerID(ctx, 2)
+	if err != nil {
+		t.Fatalf("report server id failed: %v", err)
+	}
+	if id != 0x07 || running || len(data) != 0 {
+		t.Fatalf("report server id received invalid identity; got: %v, %v, %q", id, running, data)
+	}
+}
+
+func TestFileRecord(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var mtx sync.Mutex
+	files := map[uint16][]byte{}
+	file := func(number uint16) []byte {
+		if _, ok := files[number]; !ok {
+			files[number] = make([]byte, 2*10000)
+		}
+		return files[number]
+	}
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadFileRecord: func(_ cancel.Context, _ byte, records []modbus.FileRecord) (data [][]byte, ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, r := range records {
+				data = append(data, file(r.File)[2*r.Record:2*(r.Record+r.Length)])
+			}
+			return data, 0
+		},
+		WriteFileRecord: func(_ cancel.Context, _ byte, records []modbus.FileRecord) (ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, r := range records {
+				copy(file(r.File)[2*r.Record:], r.Data)
+			}
+			return 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	if err := c.WriteFileRecord(ctx, 1,
+		modbus.FileRecord{File: 4, Record: 7, Data: []byte("hello there!")},
+		modbus.FileRecord{File: 3, Record: 9998, Data: []byte{0x12, 0x34, 0x56, 0x78}},
+	); err != nil {
+		t.Fatalf("write file record failed: %v", err)
+	}
+
+	data, err := c.ReadFileRecord(ctx, 1,
+		modbus.FileRecord{File: 4, Record: 7, Length: 6},
+		modbus.FileRecord{File: 3, Record: 9999, Length: 1},
+		modbus.FileRecord{File: 4, Record: 9, Length: 2},
+	)
+	if err != nil {
+		t.Fatalf("read file record failed: %v", err)
+	}
+	for i, want := range []string{"hello there!", "\x56\x78", "o th"} {
+		if string(data[i]) != want {
+			t.Fatalf("read file record received invalid data for record %v; want %q; got: %q", i, want, data[i])
+		}
+	}
+
+	// the references are validated before the request is sent
+	if _, err := c.ReadFileRecord(ctx, 1, modbus.FileRecord{File: 1, Record: 9999, Length: 2}); err != modbus.IllegalDataAddress {
+		t.Fatalf("read file record returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+	if err := c.WriteFileRecord(ctx, 1, modbus.FileRecord{File: 0, Record: 0, Data: []byte{0x00, 0x01}}); err != modbus.IllegalDataAddress {
+		t.Fatalf("write file record returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+}
+
+func TestMaskWriteRegister(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var mtx sync.Mutex
+	register := uint16(0x0012)
+
+	go s.Serve(ctx, &modbus.Mux{
+		MaskWriteRegister: func(_ cancel.Context, _ byte, address, andMask, orMask uint16) (ex modbus.Exception) {
+			if address != 4 {
+				return modbus.IllegalDataAddress
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			register = register&andMask | orMask&^andMask
+			return 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	if err := c.MaskWriteRegister(ctx, 1, 4, 0x00F2, 0x0025); err != nil {
+		t.Fatalf("mask write register failed: %v", err)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	// example as given by the specification
+	if register != 0x0017 {
+		t.Fatalf("mask write register computed invalid value; want %04X; got: %04X", 0x0017, register)
+	}
+	if err := c.MaskWriteRegister(ctx, 1, 5, 0xFFFF, 0x0000); err != modbus.IllegalDataAddress {
+		t.Fatalf("mask write register returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+}
+
+func TestReadFIFOQueue(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	testCases := map[uint16][]byte{
+		0: {},
+		1: []byte("hello there!"),
+		2: make([]byte, 62),
+	}
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadFIFOQueue: func(_ cancel.Context, _ byte, address uint16) (values []byte, ex modbus.Exception) {
+			if values, ok := testCases[address]; ok {
+				return values, 0
+			}
+			// exceeds the limit of 31 registers, at the address 4 the count wraps around 16 bit
+			return make([]byte, 64+2*0xFFFF*int(address-3)), 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	for address, want := range testCases {
+		values, err := c.ReadFIFOQueue(ctx, 1, address)
+		if err != nil {
+			t.Fatalf("read fifo queue failed: %v", err)
+		}
+		if !bytes.Equal(values, want) {
+			t.Fatalf("read fifo queue received invalid values at address %v; want %v; got: %v", address, want, values)
+		}
+	}
+	for _, address := range []uint16{3, 4} {
+		if _, err := c.ReadFIFOQueue(ctx, 1, address); err != modbus.IllegalDataValue {
+			t.Fatalf("read fifo queue returned unexpected error; want %v; got: %v", modbus.IllegalDataValue, err)
+		}
+	}
+}
+
+func TestReadDeviceIdentification(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	objects := modbus.DeviceIdentification{
+		modbus.ObjectVendorName:         "GoAethereal",
+		modbus.ObjectProductCode:        "MB-1",
+		modbus.ObjectMajorMinorRevision: "V1.2",
+		modbus.ObjectProductName:        "modbus",
+	}
+	// the extended objects exceed a single response
+	for id := byte(0x80); id < 0x84; id++ {
+		objects[id] = string(bytes.Repeat([]byte{id}, 100))
+	}
+
+	cfg := modbus.Config{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1342"}
+	s, c := &modbus.Server{Config: cfg, Identification: objects}, &modbus.Client{Config: cfg}
+	defer serve(ctx, s, &modbus.Mux{})()
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	testCases := map[byte][]byte{
+		modbus.BasicDeviceIdentification:    {0x00, 0x01, 0x02},
+		modbus.RegularDeviceIdentification:  {0x00, 0x01, 0x02, 0x04},
+		modbus.ExtendedDeviceIdentification: {0x00, 0x01, 0x02, 0x04, 0x80, 0x81, 0x82, 0x83},
+	}
+	for code, ids := range testCases {
+		res, err := c.ReadDeviceIdentification(ctx, 1, code, 0x00)
+		if err != nil {
+			t.Fatalf("read device identification %v failed: %v", code, err)
+		}
+		if len(res) != len(ids) {
+			t.Fatalf("read device identification %v received invalid number of objects; want %v; got: %v", code, len(ids), len(res))
+		}
+		for _, id := range ids {
+			if res[id] != objects[id] {
+				t.Fatalf("read device identification %v received invalid object %v; want %q; got: %q", code, id, objects[id], res[id])
+			}
+		}
+	}
+
+	res, err := c.ReadDeviceIdentification(ctx, 1, modbus.IndividualDeviceIdentification, 0x81)
+	if err != nil {
+		t.Fatalf("read device identification failed: %v", err)
+	}
+	if len(res) != 1 || res[0x81] != objects[0x81] {
+		t.Fatalf("read device identification received invalid objects; got: %q", res)
+	}
+	if _, err := c.ReadDeviceIdentification(ctx, 1, modbus.IndividualDeviceIdentification, 0x03); err != modbus.IllegalDataAddress {
+		t.Fatalf("read device identification returned unexpected error; want %v; got: %v", modbus.IllegalDataAddress, err)
+	}
+}
+
+func TestEncapsulatedInterfaceTransport(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	cfg := modbus.Config{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1342"}
+	s, c := &modbus.Server{Config: cfg, Identification: modbus.DeviceIdentification{modbus.ObjectVendorName: "GoAethereal"}}, &modbus.Client{Config: cfg}
+	defer serve(ctx, s, &modbus.Mux{
+		EncapsulatedInterfaceTransport: map[byte]func(ctx cancel.Context, uid byte, data []byte) (res []byte, ex modbus.Exception){
+			// reverses the data of the request
+			0x0D: func(_ cancel.Context, _ byte, data []byte) (res []byte, ex modbus.Exception) {
+				for i := len(data) - 1; i >= 0; i-- {
+					res = append(res, data[i])
+				}
+				return res, 0
+			},
+		},
+	})()
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	res, err := c.EncapsulatedInterfaceTransport(ctx, 1, 0x0D, []byte("hello there!"))
+	if err != nil {
+		t.Fatalf("encapsulated interface transport failed: %v", err)
+	}
+	if string(res) != "!ereht olleh" {
+		t.Fatalf("encapsulated interface transport received invalid data; want %q; got: %q", "!ereht olleh", res)
+	}
+	if _, err := c.EncapsulatedInterfaceTransport(ctx, 1, 0x42, nil); err != modbus.IllegalFunction {
+		t.Fatalf("encapsulated interface transport returned unexpected error; want %v; got: %v", modbus.IllegalFunction, err)
+	}
+	// device identification is still answered by the server
+	objects, err := c.ReadDeviceIdentification(ctx, 1, modbus.BasicDeviceIdentification, 0x00)
+	if err != nil {
+		t.Fatalf("read device identification failed: %v", err)
+	}
+	if objects[modbus.ObjectVendorName] != "GoAethereal" {
+		t.Fatalf("read device identification received invalid objects; got: %q", objects)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var mtx sync.Mutex
+	calls := 0
+	busy := func() (ex modbus.Exception) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		// the device is busy for the first two attempts
+		if calls++; calls < 3 {
+			return modbus.SlaveDeviceBusy
+		}
+		return 0
+	}
+	// attempts returns the number of calls since the previous invocation
+	attempts := func() (n int) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		n, calls = calls, 0
+		return n
+	}
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex modbus.Exception) {
+			if ex := busy(); ex != 0 {
+				return nil, ex
+			}
+			return make([]byte, 2*quantity), 0
+		},
+		WriteSingleRegister: func(_ cancel.Context, _ byte, _, _ uint16) (ex modbus.Exception) {
+			return busy()
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+
+	c := &modbus.Client{Config: cfg, Retry: modbus.Retry{Attempts: 3, Backoff: 10 * time.Millisecond}}
+	defer c.Disconnect()
+
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("read holding registers failed despite retries: %v", err)
+	}
+
+	attempts()
+	if err := c.WriteSingleRegister(ctx, 1, 0, 1); err != modbus.SlaveDeviceBusy {
+		t.Fatalf("write single register returned unexpected error; want %v; got: %v", modbus.SlaveDeviceBusy, err)
+	}
+	if n := attempts(); n != 1 {
+		t.Fatalf("write single register was retried without permission; got %v attempts", n)
+	}
+
+	c.Retry.Writes = true
+	if err := c.WriteSingleRegister(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("write single register failed despite retries: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			// the device is slow to respond for address 1
+			time.Sleep(time.Duration(address) * 300 * time.Millisecond)
+			return make([]byte, 2*quantity), 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+
+	c := &modbus.Client{Config: cfg, Timeout: 100 * time.Millisecond}
+	defer c.Disconnect()
+
+	_, err := c.ReadHoldingRegisters(ctx, 1, 1, 1)
+	var timeout *modbus.TimeoutError
+	if !errors.As(err, &timeout) || timeout.Duration != c.Timeout {
+		t.Fatalf("read holding registers returned unexpected error; want %T; got: %v", timeout, err)
+	}
+	// the late response of the previous request must not be mistaken for the current one
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("read holding registers failed: %v", err)
+	}
+	// the timeout of the client is overridden per request
+	if _, err := c.ReadHoldingRegisters(modbus.WithTimeout(ctx, time.Second), 1, 1, 1); err != nil {
+		t.Fatalf("read holding registers failed despite prolonged timeout: %v", err)
+	}
+}
+
+func TestLateResponse(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg := modbus.Config{Mode: "rtu", Kind: "tcp", Endpoint: "localhost:1338", Turnaround: 200 * time.Millisecond}
+	s, c := &modbus.Server{Config: cfg}, &modbus.Client{Config: cfg, Timeout: 100 * time.Millisecond}
+
+	var busy sync.Mutex
+	ctx := cancel.New()
+	defer serve(ctx, s, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			// like a serial device, requests are processed one after another
+			busy.Lock()
+			defer busy.Unlock()
+			// the device answers address 1 after the timeout of the client
+			time.Sleep(time.Duration(address) * 150 * time.Millisecond)
+			return []byte{0x00, byte(address)}, 0
+		},
+	})()
+	defer c.Disconnect()
+
+	time.Sleep(250 * time.Millisecond)
+
+	var timeout *modbus.TimeoutError
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 1, 1); !errors.As(err, &timeout) {
+		t.Fatalf("read holding registers returned unexpected error; want %T; got: %v", timeout, err)
+	}
+	// the serial line can´t tell the responses apart, hence the late one must be dropped
+	res, err := c.ReadHoldingRegisters(ctx, 1, 0, 1)
+	if want := []byte{0x00, 0x00}; err != nil || !bytes.Equal(res, want) {
+		t.Fatalf("read holding registers returned unexpected response; want % X; got: % X, %v", want, res, err)
+	}
+}
+
+func TestTypedRegisters(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var mtx sync.Mutex
+	// the device stores its values word swapped
+	registers := modbus.CDAB.EncodeFloat32s(1.5, -2.25, 100)
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if int(address+quantity)*2 > len(registers) {
+				return nil, modbus.IllegalDataAddress
+			}
+			return append([]byte(nil), registers[2*address:2*(address+quantity)]...), 0
+		},
+		WriteMultipleRegisters: func(_ cancel.Context, _ byte, address uint16, values []byte) (ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if int(address)*2+len(values) > len(registers) {
+				return modbus.IllegalDataAddress
+			}
+			copy(registers[2*address:], values)
+			return 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	// per request order
+	values, err := c.ReadFloat32s(modbus.WithOrder(ctx, modbus.CDAB), 1, 0, 3)
+	if err != nil {
+		t.Fatalf("read float32s failed: %v", err)
+	}
+	if len(values) != 3 || values[0] != 1.5 || values[1] != -2.25 || values[2] != 100 {
+		t.Fatalf("read float32s returned invalid values; got: %v", values)
+	}
+	// the default big endian order misinterprets the values
+	if values, err := c.ReadFloat32s(ctx, 1, 0, 1); err != nil || values[0] == 1.5 {
+		t.Fatalf("read float32s ignored the order; got: %v, %v", values, err)
+	}
+	// per device order, combined with a per request timeout
+	d := &modbus.Client{Config: cfg, Order: modbus.CDAB}
+	defer d.Disconnect()
+	if err := d.WriteFloat32s(modbus.WithTimeout(ctx, time.Second), 1, 2, 42.5); err != nil {
+		t.Fatalf("write float32s failed: %v", err)
+	}
+	values, err = c.ReadFloat32s(modbus.WithTimeout(modbus.WithOrder(ctx, modbus.CDAB), time.Second), 1, 2, 2)
+	if err != nil {
+		t.Fatalf("read float32s failed: %v", err)
+	}
+	if values[0] != 42.5 || values[1] != 100 {
+		t.Fatalf("write float32s wrote invalid values; got: %v", values)
+	}
+	if _, err := c.ReadFloat64s(ctx, 1, 0, 32); err != modbus.IllegalDataValue {
+		t.Fatalf("read float64s exceeding the limit returned unexpected error; want %v; got: %v", modbus.IllegalDataValue, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var (
+		mtx       sync.Mutex
+		requests  int
+		coils     = make([]bool, 16)
+		registers = make([]byte, 2*64)
+	)
+	copy(registers[2*40:], modbus.CDAB.EncodeFloat32s(230.5))
+	copy(registers[2*42:], modbus.ABCD.EncodeInt32s(-7))
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadCoils: func(_ cancel.Context, _ byte, address, quantity uint16) (res []bool, ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			requests++
+			return append([]bool(nil), coils[address:address+quantity]...), 0
+		},
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			requests++
+			return append([]byte(nil), registers[2*address:2*(address+quantity)]...), 0
+		},
+		WriteMultipleCoils: func(_ cancel.Context, _ byte, address uint16, status []bool) (ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			requests++
+			copy(coils[address:], status)
+			return 0
+		},
+		WriteMultipleRegisters: func(_ cancel.Context, _ byte, address uint16, values []byte) (ex modbus.Exception) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			requests++
+			copy(registers[2*address:], values)
+			return 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	type device struct {
+		Setpoint uint16    `modbus:"hr,0"`
+		Limits   [2]uint32 `modbus:"hr,2"`
+		Voltage  float64   `modbus:"hr,40,float32,cdab,ro"`
+		Offset   int       `modbus:"hr,42,int32"`
+		Enabled  bool      `modbus:"co,3"`
+		Relays   [2]bool   `modbus:"co,8"`
+	}
+
+	want := device{Setpoint: 1200, Limits: [2]uint32{100000, 200000}, Voltage: 1, Offset: 12, Enabled: true, Relays: [2]bool{false, true}}
+	if err := c.Marshal(ctx, 1, &want); err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	mtx.Lock()
+	// registers 0 and 2-5, 42-43 and coils 3, 8-9 are written by separate requests
+	if requests != 5 {
+		t.Errorf("marshal issued unexpected number of requests; want 5; got: %v", requests)
+	}
+	requests = 0
+	mtx.Unlock()
+
+	var got device
+	if err := c.Unmarshal(ctx, 1, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	// the read only voltage remains untouched
+	want.Voltage = 230.5
+	if got != want {
+		t.Fatalf("unmarshal returned invalid values; want: %+v; got: %+v", want, got)
+	}
+	mtx.Lock()
+	// registers 0, 2-5, 40-43 and coils 3, 8-9 are read by separate requests
+	if requests != 5 {
+		t.Errorf("unmarshal issued unexpected number of requests; want 5; got: %v", requests)
+	}
+	requests = 0
+	mtx.Unlock()
+
+	// spanning the unmapped addresses a single request per table is issued
+	g := &modbus.Client{Config: cfg, MaxGap: 40}
+	defer g.Disconnect()
+	if err := g.Unmarshal(ctx, 1, &got); err != nil || got != want {
+		t.Fatalf("unmarshal spanning gaps failed; want: %+v; got: %+v, %v", want, got, err)
+	}
+	mtx.Lock()
+	if requests != 2 {
+		t.Errorf("unmarshal spanning gaps issued unexpected number of requests; want 2; got: %v", requests)
+	}
+	mtx.Unlock()
+
+	if err := c.Unmarshal(ctx, 1, got); !errors.Is(err, modbus.ErrInvalidMapping) {
+		t.Fatalf("unmarshal of non pointer returned unexpected error; want: %v; got: %v", modbus.ErrInvalidMapping, err)
+	}
+}
+
+func TestModel(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	type meter struct {
+		Serial  string  `modbus:"ir,0,string,len=5,swap,pad=0x20"`
+		Energy  uint64  `modbus:"ir,5,bcd,len=3"`
+		Power   float32 `modbus:"ir,8,cdab"`
+		Running bool    `modbus:"hr,10,bits=0"`
+		Mode    uint8   `modbus:"hr,10,bits=4:3"`
+		Code    uint16  `modbus:"hr,10,bits=8:8,ro"`
+		Name    string  `modbus:"hr,11,len=4"`
+		Relay   bool    `modbus:"co,0"`
+	}
+
+	var m modbus.Model
+	m.Order = modbus.CDAB
+	want := meter{Serial: "MX-0042", Energy: 123456789012, Power: -1.5, Running: true, Mode: 5, Code: 0xA5, Name: "east"}
+	if err := m.Store(&want); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+	if err := m.Store(&meter{Serial: "exceeds the length"}); err != modbus.ErrDataSizeExceeded {
+		t.Fatalf("store of oversized string returned unexpected error; want: %v; got: %v", modbus.ErrDataSizeExceeded, err)
+	}
+
+	go s.Serve(ctx, &m)
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	var got meter
+	if err := c.Unmarshal(modbus.WithOrder(ctx, modbus.CDAB), 1, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got != want {
+		t.Fatalf("unmarshal returned invalid values; want: %+v; got: %+v", want, got)
+	}
+
+	// the client helpers access the same encodings
+	serial, err := c.ReadInputString(ctx, 1, 0, 5, true, ' ')
+	if err != nil || serial != "MX-0042" {
+		t.Fatalf("read input string returned unexpected result; want: %q; got: %q, %v", "MX-0042", serial, err)
+	}
+	if err := c.WriteBCD(ctx, 1, 20, 2, 20211231); err != nil {
+		t.Fatalf("write bcd failed: %v", err)
+	}
+	if v, err := c.ReadBCD(ctx, 1, 20, 2); err != nil || v != 20211231 {
+		t.Fatalf("read bcd returned unexpected result; want: %v; got: %v, %v", 20211231, v, err)
+	}
+	status := modbus.Bitfields{"running": {Offset: 0, Width: 1}, "mode": {Offset: 4, Width: 3}}
+	if err := c.WriteBitfields(ctx, 1, 10, status, map[string]uint16{"mode": 2}); err != nil {
+		t.Fatalf("write bitfields failed: %v", err)
+	}
+	values, err := c.ReadBitfields(ctx, 1, 10, status)
+	if err != nil || values["running"] != 1 || values["mode"] != 2 {
+		t.Fatalf("read bitfields returned unexpected result; got: %v, %v", values, err)
+	}
+
+	// marshal merges the bitfields into the registers, leaving the read only code untouched
+	got.Running, got.Mode, got.Code, got.Name, got.Relay = false, 7, 0, "west", true
+	if err := c.Marshal(ctx, 1, &got); err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var stored meter
+	if err := m.Load(&stored); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if stored.Running || stored.Mode != 7 || stored.Code != 0xA5 || stored.Name != "west" || !stored.Relay {
+		t.Fatalf("load returned invalid values; got: %+v", stored)
+	}
+}
+
+func TestReadPoints(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	// excerpt of a SunSpec inverter model
+	type inverter struct {
+		A      uint16 `modbus:"hr,40072"`
+		ASF    int16  `modbus:"hr,40076"`
+		PhVphA uint16 `modbus:"hr,40080"`
+		VSF    int16  `modbus:"hr,40083"`
+		TmpCab int16  `modbus:"hr,40103"`
+		TmpSF  int16  `modbus:"hr,40107"`
+		Hz     uint16 `modbus:"hr,40085"`
+		PF     int16  `modbus:"hr,40091"`
+		Temp   int16  `modbus:"ir,0"`
+	}
+	var m modbus.Model
+	if err := m.Store(&inverter{A: 1234, ASF: -2, PhVphA: 2301, VSF: -1, TmpCab: 0, TmpSF: math.MinInt16, Hz: 0xFFFF, PF: math.MinInt16, Temp: 1000}); err != nil {
+		t.Fatalf("store failed: %v", err)
+	}
+
+	go s.Serve(ctx, &m)
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	measurements, err := c.ReadPoints(ctx, 1,
+		modbus.Point{Name: "current", Register: "hr,40072", ScaleFactor: "hr,40076", Unit: "A"},
+		modbus.Point{Name: "voltage", Register: "hr,40080,uint16", ScaleFactor: "hr,40083", Unit: "V"},
+		modbus.Point{Name: "cabinet", Register: "hr,40103,int16", ScaleFactor: "hr,40107", Unit: "C"},
+		modbus.Point{Name: "frequency", Register: "hr,40085", Unit: "Hz"},
+		modbus.Point{Name: "power factor", Register: "hr,40091,int16"},
+		modbus.Point{Name: "temperature", Register: "ir,0,int16", Gain: 0.1, Offset: -40, Unit: "C"},
+	)
+	if err != nil {
+		t.Fatalf("read points failed: %v", err)
+	}
+	want := []modbus.Measurement{
+		{Name: "current", Value: 12.34, Unit: "A"},
+		{Name: "voltage", Value: 230.1, Unit: "V"},
+		{Name: "cabinet", Value: math.NaN(), Unit: "C"},
+		// the raw values of both points are not implemented
+		{Name: "frequency", Value: math.NaN(), Unit: "Hz"},
+		{Name: "power factor", Value: math.NaN()},
+		{Name: "temperature", Value: 60, Unit: "C"},
+	}
+	if len(measurements) != len(want) {
+		t.Fatalf("read points returned unexpected number of measurements; want: %v; got: %v", len(want), len(measurements))
+	}
+	for i, w := range want {
+		got := measurements[i]
+		if got.Name != w.Name || got.Unit != w.Unit || math.IsNaN(got.Value) != math.IsNaN(w.Value) ||
+			!math.IsNaN(w.Value) && math.Abs(got.Value-w.Value) > 1e-9 {
+			t.Errorf("measurement %v invalid; want: %+v; got: %+v", i, w, got)
+		}
+	}
+
+	if _, err := c.ReadPoints(ctx, 1, modbus.Point{Name: "relay", Register: "co,0"}); !errors.Is(err, modbus.ErrInvalidMapping) {
+		t.Fatalf("read points of coil returned unexpected error; want: %v; got: %v", modbus.ErrInvalidMapping, err)
+	}
+}
+
 func TestWriteMultipleCoils(t *testing.T) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -369,3 +1285,276 @@ func TestReadWriteMultipleRegisters(t *testing.T) {
 		}
 	}
 }
+
+func TestModes(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	for _, cfg := range []modbus.Config{
+		{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1338"},
+		{Mode: "rtu", Kind: "tcp", Endpoint: "localhost:1338"},
+		{Mode: "ascii", Kind: "tcp", Endpoint: "localhost:1338"},
+		{Mode: "rtu", Kind: "udp", Endpoint: "localhost:1338"},
+		{Mode: "ascii", Kind: "udp", Endpoint: "localhost:1338"},
+	} {
+		mode := cfg.Mode + " over " + cfg.Kind
+		s, c := &modbus.Server{Config: cfg}, &modbus.Client{Config: cfg}
+
+		ctx := cancel.New()
+		stop := serve(ctx, s, &modbus.Mux{
+			ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+				if address != 10 {
+					return nil, modbus.IllegalDataAddress
+				}
+				return want[:2*quantity], 0
+			},
+		})
+		time.Sleep(250 * time.Millisecond)
+
+		res, err := c.ReadHoldingRegisters(ctx, 1, 10, 5)
+		if err != nil {
+			t.Fatalf("read holding registers in %v mode failed: %v", mode, err)
+		}
+		if string(res) != string(want) {
+			t.Fatalf("read holding registers in %v mode received invalid values; want %v; got: %v", mode, want, res)
+		}
+		if _, err := c.ReadHoldingRegisters(ctx, 1, 11, 5); err != modbus.IllegalDataAddress {
+			t.Fatalf("read holding registers in %v mode returned unexpected error; want %v; got: %v", mode, modbus.IllegalDataAddress, err)
+		}
+		c.Disconnect()
+		stop()
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, cfg := range []modbus.Config{
+		{Mode: "rtu", Kind: "tcp", Endpoint: "localhost:1338", Turnaround: 50 * time.Millisecond},
+		{Mode: "ascii", Kind: "udp", Endpoint: "localhost:1338", Turnaround: 50 * time.Millisecond},
+	} {
+		mode := cfg.Mode + " over " + cfg.Kind
+		s, c := &modbus.Server{Config: cfg}, &modbus.Client{Config: cfg}
+
+		written := make(chan byte, 2)
+		ctx := cancel.New()
+		stop := serve(ctx, s, &modbus.Mux{
+			WriteSingleRegister: func(_ cancel.Context, uid byte, _, _ uint16) (ex modbus.Exception) {
+				written <- uid
+				return 0
+			},
+		})
+		time.Sleep(250 * time.Millisecond)
+
+		start := time.Now()
+		if err := c.WriteSingleRegister(ctx, 0, 1, 2); err != nil {
+			t.Fatalf("broadcast in %v mode failed: %v", mode, err)
+		}
+		if d := time.Since(start); d < cfg.Turnaround {
+			t.Fatalf("broadcast in %v mode returned before the turnaround delay; got: %v", mode, d)
+		}
+		// reads can´t be broadcast, they are rejected before being sent
+		if _, err := c.ReadHoldingRegisters(ctx, 0, 1, 1); err != modbus.ErrInvalidParameter {
+			t.Fatalf("broadcast read in %v mode returned unexpected error; want %v; got: %v", mode, modbus.ErrInvalidParameter, err)
+		}
+		// the broadcast is executed but not answered, hence the next response belongs to the next request
+		if err := c.WriteSingleRegister(ctx, 1, 1, 2); err != nil {
+			t.Fatalf("write single register in %v mode failed: %v", mode, err)
+		}
+		for _, want := range []byte{0, 1} {
+			if uid := <-written; uid != want {
+				t.Fatalf("write single register in %v mode executed for invalid unit; want %v; got: %v", mode, want, uid)
+			}
+		}
+		c.Disconnect()
+		stop()
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg := modbus.Config{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1341"}
+	h := &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex modbus.Exception) {
+			return make([]byte, 2*quantity), 0
+		},
+	}
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	c := &modbus.Client{Config: cfg, Reconnect: modbus.Reconnect{Backoff: 50 * time.Millisecond, MaxBackoff: 200 * time.Millisecond, Jitter: 0.2}}
+	states := c.Subscribe(ctx)
+
+	// expect awaits the given sequence of states, skipping repeated failed attempts
+	expect := func(want ...modbus.Status) (last modbus.State) {
+		t.Helper()
+		for _, status := range want {
+			for {
+				select {
+				case last = <-states:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("client didn´t reach the %v state", status)
+				}
+				if last.Status == status {
+					break
+				}
+			}
+		}
+		return last
+	}
+
+	stop := serve(cancel.New(), &modbus.Server{Config: cfg}, h)
+	time.Sleep(250 * time.Millisecond)
+
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("read holding registers failed: %v", err)
+	}
+	expect(modbus.Connecting, modbus.Connected)
+
+	// the loss of the connection is reported with its cause
+	stop()
+	if state := expect(modbus.Disconnected); state.Err == nil {
+		t.Fatalf("client reported the loss of the connection without a cause")
+	}
+	// the supervisor keeps redialing in the background until the server is back
+	expect(modbus.Connecting, modbus.Disconnected, modbus.Connecting, modbus.Disconnected)
+	stop = serve(cancel.New(), &modbus.Server{Config: cfg}, h)
+	defer stop()
+	expect(modbus.Connected)
+
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("read holding registers failed after reconnect: %v", err)
+	}
+
+	c.Disconnect()
+	if state := expect(modbus.Disconnected); state.Err != nil {
+		t.Fatalf("client reported a cause for the deliberate disconnect: %v", state.Err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	var mtx sync.Mutex
+	active, peak := 0, 0
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex modbus.Exception) {
+			mtx.Lock()
+			if active++; active > peak {
+				peak = active
+			}
+			mtx.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mtx.Lock()
+			active--
+			mtx.Unlock()
+			return make([]byte, 2*quantity), 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+
+	c := &modbus.Client{Config: cfg, MaxInFlight: 2}
+	defer c.Disconnect()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+				t.Errorf("read holding registers failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if peak != 2 {
+		t.Fatalf("server processed invalid number of concurrent requests; want 2; got: %v", peak)
+	}
+}
+
+func TestUDP(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg := modbus.Config{Mode: "tcp", Kind: "udp", Endpoint: "localhost:1339"}
+	s := &modbus.Server{Config: cfg}
+
+	ctx := cancel.New()
+	defer serve(ctx, s, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, uid byte, address, _ uint16) (res []byte, ex modbus.Exception) {
+			return []byte{uid, byte(address)}, 0
+		},
+	})()
+
+	time.Sleep(250 * time.Millisecond)
+
+	// every client uses its own source port, replies must reach the originating one
+	for uid := byte(1); uid <= 3; uid++ {
+		c := &modbus.Client{Config: cfg}
+		for address := uint16(0); address < 3; address++ {
+			res, err := c.ReadHoldingRegisters(ctx, uid, address, 1)
+			if err != nil {
+				t.Fatalf("udp client %v failed to read holding registers: %v", uid, err)
+			}
+			if res[0] != uid || res[1] != byte(address) {
+				t.Fatalf("udp client %v received invalid values at address %v; got: %v", uid, address, res)
+			}
+		}
+		c.Disconnect()
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	go s.Serve(ctx, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			res = make([]byte, 2*quantity)
+			for i := range res {
+				res[i] = byte(address)
+			}
+			return res, 0
+		},
+	})
+
+	time.Sleep(250 * time.Millisecond)
+	defer c.Disconnect()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(address uint16) {
+			defer wg.Done()
+			res, err := c.ReadHoldingRegisters(ctx, 1, address, 100)
+			if err != nil {
+				t.Errorf("concurrent read holding registers at address %v failed: %v", address, err)
+				return
+			}
+			for i := range res {
+				if res[i] != byte(address) {
+					t.Errorf("concurrent read holding registers at address %v received invalid value at index %v; got: %v", address, i, res[i])
+					return
+				}
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+}
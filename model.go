@@ -0,0 +1,139 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/GoAethereal/cancel"
+)
+
+var _ Handler = (*Model)(nil)
+
+// Model is an in-memory data model of a device, spanning the complete address space of the four primary tables.
+// It answers the coil and register requests of a server, while the application accesses the data by Load and Store.
+// The zero value is an empty model ready to use. A Model must not be copied after first use.
+type Model struct {
+	// Order is the byte order applied by Load and Store, unless overridden by the tags.
+	Order  Order
+	once   sync.Once
+	mtx    sync.RWMutex
+	tables [4]image
+	mux    Mux
+}
+
+// init allocates the tables and registers the callbacks of the multiplexer.
+func (m *Model) init() {
+	m.tables[coils].bits = make([]bool, 0x10000)
+	m.tables[discreteInputs].bits = make([]bool, 0x10000)
+	m.tables[holdingRegisters].regs = make([]byte, 2*0x10000)
+	m.tables[inputRegisters].regs = make([]byte, 2*0x10000)
+	m.mux = Mux{
+		ReadCoils: func(_ cancel.Context, _ byte, address, quantity uint16) (res []bool, ex Exception) {
+			return m.readBits(coils, address, quantity), 0
+		},
+		ReadDiscreteInputs: func(_ cancel.Context, _ byte, address, quantity uint16) (res []bool, ex Exception) {
+			return m.readBits(discreteInputs, address, quantity), 0
+		},
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex Exception) {
+			return m.readRegs(holdingRegisters, address, quantity), 0
+		},
+		ReadInputRegisters: func(_ cancel.Context, _ byte, address, quantity uint16) (res []byte, ex Exception) {
+			return m.readRegs(inputRegisters, address, quantity), 0
+		},
+		WriteSingleCoil: func(_ cancel.Context, _ byte, address uint16, status bool) (ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			m.tables[coils].bits[address] = status
+			return 0
+		},
+		WriteSingleRegister: func(_ cancel.Context, _ byte, address, value uint16) (ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			binary.BigEndian.PutUint16(m.tables[holdingRegisters].regs[2*int(address):], value)
+			return 0
+		},
+		WriteMultipleCoils: func(_ cancel.Context, _ byte, address uint16, status []bool) (ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			copy(m.tables[coils].bits[address:], status)
+			return 0
+		},
+		WriteMultipleRegisters: func(_ cancel.Context, _ byte, address uint16, values []byte) (ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			copy(m.tables[holdingRegisters].regs[2*int(address):], values)
+			return 0
+		},
+		MaskWriteRegister: func(_ cancel.Context, _ byte, address, andMask, orMask uint16) (ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			b := m.tables[holdingRegisters].regs[2*int(address):]
+			binary.BigEndian.PutUint16(b, binary.BigEndian.Uint16(b)&andMask|orMask&^andMask)
+			return 0
+		},
+		ReadWriteMultipleRegisters: func(_ cancel.Context, _ byte, rAddress, rQuantity, wAddress uint16, values []byte) (res []byte, ex Exception) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			// the write is performed before the read
+			copy(m.tables[holdingRegisters].regs[2*int(wAddress):], values)
+			return append([]byte(nil), m.tables[holdingRegisters].regs[2*int(rAddress):2*(int(rAddress)+int(rQuantity))]...), 0
+		},
+	}
+}
+
+// readBits returns a copy of the bits of the table.
+func (m *Model) readBits(t table, address, quantity uint16) []bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return append([]bool(nil), m.tables[t].bits[address:int(address)+int(quantity)]...)
+}
+
+// readRegs returns a copy of the register values of the table.
+func (m *Model) readRegs(t table, address, quantity uint16) []byte {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return append([]byte(nil), m.tables[t].regs[2*int(address):2*(int(address)+int(quantity))]...)
+}
+
+// Handle answers the coil and register requests from the data of the model.
+// Any other request is answered with modbus.IllegalFunction.
+func (m *Model) Handle(ctx cancel.Context, uid, code byte, req []byte) (res []byte, ex Exception) {
+	m.once.Do(m.init)
+	return m.mux.Handle(ctx, uid, code, req)
+}
+
+// Load sets the mapped fields of the struct pointed to by v from the data of the model.
+// See Client.Unmarshal for the format of the tags.
+func (m *Model) Load(v interface{}) (err error) {
+	rv, l, err := mapping(v)
+	if err != nil {
+		return err
+	}
+	m.once.Do(m.init)
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	for _, p := range l {
+		if err := p.decode(rv.FieldByIndex(p.index), m.tables[p.table], int(p.address), m.Order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Store writes the mapped fields of the struct pointed to by v into the model, including read only tables and fields.
+// See Client.Unmarshal for the format of the tags. On failure the fields preceding the offending one are stored.
+func (m *Model) Store(v interface{}) (err error) {
+	rv, l, err := mapping(v)
+	if err != nil {
+		return err
+	}
+	m.once.Do(m.init)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, p := range l {
+		if err := p.encode(rv.FieldByIndex(p.index), m.tables[p.table], int(p.address), m.Order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package modbus
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// Reconnect defines how the client re-establishes its connection.
+// The zero value disables the supervisor, a lost connection is then re-dialed on the next request.
+type Reconnect struct {
+	// Backoff is the delay before redialing a lost connection, it´s doubled for each failed attempt.
+	// Requests issued while waiting fail with the cause of the last failure, instead of dialing themselves.
+	Backoff time.Duration
+	// MaxBackoff limits the delay between two attempts, no limit applies if zero.
+	MaxBackoff time.Duration
+	// Jitter randomizes each delay by up to the given fraction, e.g. 0.2 for ±20%.
+	// This prevents multiple clients from reconnecting in lockstep.
+	Jitter float64
+}
+
+// enabled reports whether the connection is supervised.
+func (r Reconnect) enabled() bool {
+	return r.Backoff > 0
+}
+
+// backoff returns the randomized delay following the given number of failed attempts.
+func (r Reconnect) backoff(attempt int) time.Duration {
+	d := backoff(r.Backoff, r.MaxBackoff, attempt)
+	if r.Jitter > 0 {
+		d += time.Duration(r.Jitter * (2*rand.Float64() - 1) * float64(d))
+	}
+	return d
+}
+
+// Status is the state of the clients connection.
+type Status int
+
+const (
+	// Disconnected signals that no connection is established.
+	Disconnected Status = iota
+	// Connecting signals that the endpoint is dialed.
+	Connecting
+	// Connected signals that the connection was successfully established.
+	Connected
+)
+
+// String returns a human readable representation of the status.
+func (s Status) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	}
+	return "unknown"
+}
+
+// State is a change of the clients connection.
+type State struct {
+	Status Status
+	// Err holds the cause of a disconnect or failed attempt.
+	// It´s nil if the client was disconnected deliberately.
+	Err error
+}
+
+// supervisor holds the reconnection state of a client.
+// Except for the subscribers its fields are guarded by the mutex of the client.
+type supervisor struct {
+	// attempt counts the consecutive failures, including the loss of a connection
+	attempt int
+	// next is the earliest time of the next attempt
+	next time.Time
+	// err is the cause of the last failure
+	err error
+	// stop cancels the running supervisor, it´s nil if none is running
+	stop *cancel.Signal
+	mtx  sync.Mutex
+	subs map[chan State]struct{}
+}
+
+// subscribe registers a new subscriber, which is removed and closed once ctx is canceled.
+func (s *supervisor) subscribe(ctx cancel.Context) <-chan State {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[chan State]struct{})
+	}
+	ch := make(chan State, 16)
+	s.subs[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		delete(s.subs, ch)
+		close(ch)
+	}()
+	return ch
+}
+
+// notify passes the state on to all subscribers, without waiting for slow ones.
+func (s *supervisor) notify(state State) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a stream of the connection state changes of the client.
+// The channel is closed once ctx is canceled. Events are dropped if the receiver falls behind.
+func (c *Client) Subscribe(ctx cancel.Context) <-chan State {
+	return c.sv.subscribe(ctx)
+}
+
+// dial establishes a new connection, unless the backoff of a previous failure is still pending.
+// Must be called with the lock held.
+func (c *Client) dial(ctx cancel.Context) (err error) {
+	if c.Reconnect.enabled() && time.Now().Before(c.sv.next) {
+		return c.sv.err
+	}
+	c.sv.notify(State{Status: Connecting})
+	con, err := c.Config.connection(ctx, c.f)
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+	c.c, c.sv.attempt, c.sv.err = con, 0, nil
+	// watch the connection for its loss
+	con.rx(cancel.New(), func(_ []byte, err error) (quit bool) {
+		if err != nil {
+			go c.lost(con, err)
+		}
+		return err != nil
+	})
+	c.sv.notify(State{Status: Connected})
+	return nil
+}
+
+// fail records the failure and schedules the next attempt.
+// Must be called with the lock held.
+func (c *Client) fail(err error) {
+	c.sv.attempt++
+	c.sv.err, c.sv.next = err, time.Now().Add(c.Reconnect.backoff(c.sv.attempt))
+	c.sv.notify(State{Status: Disconnected, Err: err})
+}
+
+// lost handles the unexpected loss of the connection and starts the supervisor if enabled.
+func (c *Client) lost(con connection, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	// the connection was deliberately closed or already replaced
+	if c.c != con {
+		return
+	}
+	c.c = nil
+	c.fail(err)
+	if !c.Reconnect.enabled() || c.sv.stop != nil {
+		return
+	}
+	c.sv.stop = cancel.New()
+	go c.supervise(c.sv.stop)
+}
+
+// supervise redials the connection until it´s established or the client disconnected.
+func (c *Client) supervise(stop *cancel.Signal) {
+	defer func() {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		// a disconnect already released the supervisor, a new one might be running by now
+		if c.sv.stop == stop {
+			c.sv.stop = nil
+		}
+	}()
+	for {
+		c.mtx.Lock()
+		wait := time.Until(c.sv.next)
+		c.mtx.Unlock()
+		select {
+		case <-stop.Done():
+			return
+		case <-time.After(wait):
+		}
+		if c.redial(stop) {
+			return
+		}
+	}
+}
+
+// redial dials the connection on behalf of the supervisor and reports whether the supervisor is done.
+// Nothing is dialed once the supervisor got released by a disconnect, or if a request dialed meanwhile.
+func (c *Client) redial(stop *cancel.Signal) (done bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	select {
+	case <-stop.Done():
+		return true
+	default:
+	}
+	switch {
+	case c.sv.stop != stop:
+		return true
+	case c.c != nil && c.c.ready():
+		return true
+	}
+	if c.f == nil {
+		var err error
+		if c.f, err = c.Config.framer(stop); err != nil {
+			return true
+		}
+	}
+	return c.dial(stop) == nil
+}
@@ -0,0 +1,74 @@
+package modbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// broken is a connection which failed right away.
+type broken struct{}
+
+func (broken) ready() bool                               { return false }
+func (broken) close()                                    {}
+func (broken) tx(_ cancel.Context, _ []byte) (err error) { return errors.New("broken pipe") }
+func (broken) rx(_ cancel.Context, _ func(adu []byte, err error) (quit bool)) <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func TestReconnectAfterDisconnect(t *testing.T) {
+	c := &Client{Reconnect: Reconnect{Backoff: time.Hour}}
+	defer c.Disconnect()
+
+	for i := 0; i < 3; i++ {
+		con := &broken{}
+		c.mtx.Lock()
+		c.c = con
+		c.mtx.Unlock()
+		c.lost(con, errors.New("connection reset"))
+
+		c.mtx.Lock()
+		running := c.sv.stop != nil
+		c.mtx.Unlock()
+		// a supervisor is started for every loss following a disconnect
+		if !running {
+			t.Fatalf("no supervisor started for the loss of the connection after %v disconnects", i)
+		}
+		c.Disconnect()
+	}
+}
+
+func TestRedialAfterDisconnect(t *testing.T) {
+	c := &Client{
+		Config:    Config{Mode: "tcp", Kind: "tcp", Endpoint: "localhost:1"},
+		Reconnect: Reconnect{Backoff: time.Hour},
+	}
+	ctx := cancel.New()
+	defer ctx.Cancel()
+	states := c.Subscribe(ctx)
+
+	// the supervisor is released by the disconnect right before it redials
+	stop := cancel.New()
+	c.mtx.Lock()
+	c.sv.stop = stop
+	c.mtx.Unlock()
+	c.Disconnect()
+
+	if !c.redial(stop) {
+		t.Fatalf("released supervisor isn´t done")
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.c != nil || c.sv.attempt != 0 || c.sv.err != nil {
+		t.Fatalf("released supervisor dialed the connection")
+	}
+	select {
+	case state := <-states:
+		t.Fatalf("released supervisor reported the state %v", state.Status)
+	default:
+	}
+}
@@ -0,0 +1,96 @@
+package modbus
+
+import "encoding/binary"
+
+// FileRecord references a group of registers inside a file, as accessed by the
+// function codes 0x14 (read file record) and 0x15 (write file record).
+type FileRecord struct {
+	// File is the file number, ranging from 1 to 0xFFFF.
+	File uint16
+	// Record is the starting record number, ranging from 0 to 9999.
+	Record uint16
+	// Length is the number of registers, for writes it´s implied by Data.
+	Length uint16
+	// Data holds the register values to write.
+	Data []byte
+}
+
+// check validates the reference of the file record.
+func (r FileRecord) check() Exception {
+	switch {
+	case r.Length < 1:
+		return IllegalDataValue
+	case r.File == 0 || r.Record > 9999 || int(r.Record)+int(r.Length)-1 > 9999:
+		return IllegalDataAddress
+	}
+	return 0
+}
+
+// fileRecords decodes the sub-requests of the function codes 0x14 and 0x15.
+// If write is set each sub-request is followed by its register values.
+func fileRecords(req []byte, write bool) (records []FileRecord, ex Exception) {
+	if len(req) < 1 || int(req[0]) != len(req[1:]) {
+		return nil, IllegalDataValue
+	}
+	for req = req[1:]; len(req) > 0; {
+		switch {
+		case len(req) < 7:
+			return nil, IllegalDataValue
+		case req[0] != 0x06:
+			return nil, IllegalDataAddress
+		}
+		r := FileRecord{
+			File:   binary.BigEndian.Uint16(req[1:]),
+			Record: binary.BigEndian.Uint16(req[3:]),
+			Length: binary.BigEndian.Uint16(req[5:]),
+		}
+		req = req[7:]
+		if write {
+			if len(req) < 2*int(r.Length) {
+				return nil, IllegalDataValue
+			}
+			r.Data, req = req[:2*r.Length], req[2*r.Length:]
+		}
+		if ex := r.check(); ex != 0 {
+			return nil, ex
+		}
+		records = append(records, r)
+	}
+	if len(records) == 0 {
+		return nil, IllegalDataValue
+	}
+	return records, 0
+}
+
+// putFileRecords encodes the given file records as sub-requests of the function codes 0x14 and 0x15.
+// If write is set each sub-request is followed by its register values and the length is implied by them.
+func putFileRecords(records []FileRecord, write bool) (req []byte, ex Exception) {
+	if len(records) == 0 {
+		return nil, IllegalDataValue
+	}
+	req = []byte{0}
+	for _, r := range records {
+		if write {
+			if len(r.Data)%2 != 0 {
+				return nil, IllegalDataValue
+			}
+			r.Length = uint16(len(r.Data) / 2)
+		}
+		if ex := r.check(); ex != 0 {
+			return nil, ex
+		}
+		req = append(req, put(7, byte(0x06), r.File, r.Record, r.Length)...)
+		if write {
+			req = append(req, r.Data...)
+		}
+	}
+	limit := 0xF5
+	if write {
+		limit = 0xFB
+	}
+	if len(req)-1 > limit {
+		return nil, IllegalDataValue
+	}
+	req[0] = byte(len(req) - 1)
+	return req, 0
+}
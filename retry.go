@@ -0,0 +1,83 @@
+package modbus
+
+import (
+	"context"
+	"time"
+)
+
+// Retry defines how the client repeats failed requests.
+// The zero value disables retries, every request is attempted exactly once.
+type Retry struct {
+	// Attempts is the maximum number of attempts per request, including the first one.
+	Attempts int
+	// Backoff is the delay before the first retry, it´s doubled for each further retry.
+	Backoff time.Duration
+	// MaxBackoff limits the delay between two attempts, no limit applies if zero.
+	MaxBackoff time.Duration
+	// Retryable, if set, decides whether a failed request is repeated.
+	// By default transport errors and the exceptions SlaveDeviceBusy, GatewayPathUnavailable
+	// and GatewayTargetDeviceFailedToRespond are retried.
+	Retryable func(err error) bool
+	// Writes permits retrying requests which modify the state of the remote device.
+	// As the device might have executed a request whose response got lost, a retry could apply it twice.
+	// Hence only reads are retried unless explicitly allowed.
+	Writes bool
+}
+
+// retries reports whether the request is repeated after the failed attempt.
+func (r Retry) retries(attempt int, code byte, req []byte, err error) bool {
+	switch {
+	case attempt >= r.Attempts:
+		return false
+	case !r.Writes && !idempotent(code, req):
+		return false
+	case r.Retryable != nil:
+		return r.Retryable(err)
+	}
+	return retryable(err)
+}
+
+// backoff returns the delay following the given attempt.
+func (r Retry) backoff(attempt int) time.Duration {
+	return backoff(r.Backoff, r.MaxBackoff, attempt)
+}
+
+// backoff doubles the base delay for each attempt after the first, limited by max if not zero.
+func backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		if max > 0 && d >= max {
+			break
+		}
+		d *= 2
+	}
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// idempotent reports whether the request only reads from the remote device.
+// Of the encapsulated interface transport only the read device identification is known to be free of side effects.
+func idempotent(code byte, req []byte) bool {
+	switch code {
+	case 0x01, 0x02, 0x03, 0x04, 0x07, 0x0B, 0x0C, 0x11, 0x14, 0x18:
+		return true
+	case 0x2B:
+		return len(req) > 0 && req[0] == 0x0E
+	}
+	return false
+}
+
+// retryable is the default classification of errors worth a retry.
+func retryable(err error) bool {
+	switch err {
+	case SlaveDeviceBusy, GatewayPathUnavailable, GatewayTargetDeviceFailedToRespond:
+		return true
+	case context.Canceled, ErrInvalidParameter, ErrDataSizeExceeded:
+		return false
+	}
+	// any other exception is the deliberate answer of the device
+	_, ok := err.(Exception)
+	return !ok
+}
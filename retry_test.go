@@ -0,0 +1,46 @@
+package modbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	r := Retry{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := r.backoff(attempt + 1); got != want*time.Millisecond {
+			t.Fatalf("backoff after attempt %v is invalid; want %v; got: %v", attempt+1, want*time.Millisecond, got)
+		}
+	}
+}
+
+func TestRetryRetries(t *testing.T) {
+	r := Retry{Attempts: 3}
+	testCases := []struct {
+		attempt int
+		code    byte
+		req     []byte
+		err     error
+		want    bool
+	}{
+		{1, 0x03, nil, errors.New("broken pipe"), true},
+		{2, 0x03, nil, SlaveDeviceBusy, true},
+		{3, 0x03, nil, SlaveDeviceBusy, false},
+		{1, 0x03, nil, IllegalDataAddress, false},
+		{1, 0x03, nil, ErrInvalidParameter, false},
+		{1, 0x06, nil, SlaveDeviceBusy, false},
+		{1, 0x2B, []byte{0x0E, 0x01, 0x00}, GatewayTargetDeviceFailedToRespond, true},
+		{1, 0x2B, []byte{0x0D}, GatewayTargetDeviceFailedToRespond, false},
+	}
+	for _, tc := range testCases {
+		if got := r.retries(tc.attempt, tc.code, tc.req, tc.err); got != tc.want {
+			t.Fatalf("retry of function code %v after attempt %v with error %v is invalid; want %v; got: %v", tc.code, tc.attempt, tc.err, tc.want, got)
+		}
+	}
+	// writes are only retried when explicitly allowed
+	r.Writes = true
+	if !r.retries(1, 0x06, nil, SlaveDeviceBusy) {
+		t.Fatalf("retry of permitted write was refused")
+	}
+}
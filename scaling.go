@@ -0,0 +1,119 @@
+package modbus
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// Point describes a register holding the raw value of an engineering value, such as a SunSpec model point.
+// The engineering value is computed by raw * Gain * 10^sf + Offset, where sf is the value of the scale factor register.
+type Point struct {
+	// Name identifies the point in the returned measurements.
+	Name string
+	// Register maps the raw value in the format of the struct tags, e.g. "hr,40083,int16", see Client.Unmarshal.
+	// Without a type it´s read as uint16. As defined by SunSpec for points not implemented, the raw values
+	// 0x8000 of an int16 and 0xFFFF of an uint16 register result in NaN.
+	Register string
+	// Gain scales the raw value, a zero gain is taken as 1.
+	Gain float64
+	// Offset is added to the scaled value.
+	Offset float64
+	// ScaleFactor, if set, maps the register holding the power of ten applied to the raw value, e.g. "hr,40084".
+	// It´s read as int16 unless stated otherwise, the SunSpec value 0x8000 (not implemented) results in NaN.
+	ScaleFactor string
+	// Unit of the engineering value, e.g. "V", passed on to the measurement.
+	Unit string
+}
+
+// Measurement is the engineering value of a point.
+type Measurement struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+// scaled is a point parsed into its raw value and scale factor mappings.
+type scaled struct {
+	Point
+	raw, sf *point
+}
+
+// parse parses the mappings of the point.
+func (p Point) parse() (s scaled, err error) {
+	s.Point = p
+	if s.raw, err = parsePoint(reflect.TypeOf(uint16(0)), p.Register); err != nil {
+		return s, fmt.Errorf("%w: point %s: %v", ErrInvalidMapping, p.Name, err)
+	}
+	if s.raw.table.bits() {
+		return s, fmt.Errorf("%w: point %s: coils and discrete inputs can´t be scaled", ErrInvalidMapping, p.Name)
+	}
+	if p.ScaleFactor == "" {
+		return s, nil
+	}
+	if s.sf, err = parsePoint(reflect.TypeOf(int16(0)), p.ScaleFactor); err != nil || s.sf.table.bits() {
+		return s, fmt.Errorf("%w: point %s: invalid scale factor %q", ErrInvalidMapping, p.Name, p.ScaleFactor)
+	}
+	return s, nil
+}
+
+// value computes the engineering value from the raw value and scale factor.
+func (s scaled) value(raw, sf float64) float64 {
+	switch {
+	case s.raw.kind == kindInt16 && raw == math.MinInt16, s.raw.kind == kindUint16 && raw == math.MaxUint16:
+		return math.NaN()
+	}
+	gain := s.Gain
+	if gain == 0 {
+		gain = 1
+	}
+	if s.sf != nil {
+		if sf == math.MinInt16 {
+			return math.NaN()
+		}
+		gain *= math.Pow10(int(sf))
+	}
+	return raw*gain + s.Offset
+}
+
+// ReadPoints reads the raw values and scale factors of the points and returns their engineering values in the same order.
+// All registers are fetched by the fewest possible requests, as done by Unmarshal.
+func (c *Client) ReadPoints(ctx cancel.Context, uid byte, points ...Point) (measurements []Measurement, err error) {
+	var l layout
+	parsed := make([]scaled, len(points))
+	for i, p := range points {
+		if parsed[i], err = p.parse(); err != nil {
+			return nil, err
+		}
+		l = append(l, parsed[i].raw)
+		if parsed[i].sf != nil {
+			l = append(l, parsed[i].sf)
+		}
+	}
+	o := c.order(ctx)
+	raws := make(map[*point]float64, len(l))
+	for _, b := range plan(l, c.MaxGap, false) {
+		img, err := c.read(ctx, uid, b)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range b.points {
+			var raw float64
+			if err := p.decode(reflect.ValueOf(&raw).Elem(), img, int(p.address-b.address), o); err != nil {
+				return nil, err
+			}
+			raws[p] = raw
+		}
+	}
+	measurements = make([]Measurement, len(parsed))
+	for i, s := range parsed {
+		var sf float64
+		if s.sf != nil {
+			sf = raws[s.sf]
+		}
+		measurements[i] = Measurement{Name: s.Name, Value: s.value(raws[s.raw], sf), Unit: s.Unit}
+	}
+	return measurements, nil
+}
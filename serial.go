@@ -0,0 +1,90 @@
+package modbus
+
+import (
+	"time"
+)
+
+// Serial holds the line settings used in conjunction with the serial kind.
+// Zero values are replaced by the defaults of the modbus specification (19200 baud, 8 data bits, even parity, 1 stop bit).
+type Serial struct {
+	// BaudRate defines the transmission speed in bits per second
+	// valid rates are:
+	//	1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400
+	BaudRate int
+	// DataBits is the number of bits per character, either 7 or 8
+	DataBits int
+	// Parity defines the parity checking
+	// valid parities are:
+	//	- E	(even)
+	//	- O	(odd)
+	//	- N	(none)
+	Parity string
+	// StopBits is the number of stop bits per character, either 1 or 2
+	StopBits int
+	// Silence overwrites the inter-frame delay (t3.5) marking the end of a frame in rtu mode.
+	// By default it´s derived from the baud rate as specified.
+	// Serial adapters which deliver data in bursts (e.g. via USB) might require a greater value.
+	Silence time.Duration
+}
+
+// verify validates the serial line settings.
+func (s Serial) verify() error {
+	switch s.baudRate() {
+	case 1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400:
+	default:
+		return ErrInvalidParameter
+	}
+	switch {
+	case s.dataBits() != 7 && s.dataBits() != 8,
+		s.parity() != "E" && s.parity() != "O" && s.parity() != "N",
+		s.stopBits() != 1 && s.stopBits() != 2,
+		s.Silence < 0:
+		return ErrInvalidParameter
+	}
+	return nil
+}
+
+func (s Serial) baudRate() int {
+	if s.BaudRate == 0 {
+		return 19200
+	}
+	return s.BaudRate
+}
+
+func (s Serial) dataBits() int {
+	if s.DataBits == 0 {
+		return 8
+	}
+	return s.DataBits
+}
+
+func (s Serial) parity() string {
+	if s.Parity == "" {
+		return "E"
+	}
+	return s.Parity
+}
+
+func (s Serial) stopBits() int {
+	if s.StopBits == 0 {
+		return 1
+	}
+	return s.StopBits
+}
+
+// silence returns the time without transmission after which a frame is considered complete.
+// Above 19200 baud a fixed value of 1.75ms is used as recommended by the specification.
+func (s Serial) silence() time.Duration {
+	switch {
+	case s.Silence > 0:
+		return s.Silence
+	case s.baudRate() > 19200:
+		return 1750 * time.Microsecond
+	}
+	// a character consists of the start, data, parity and stop bits
+	bits := 1 + s.dataBits() + s.stopBits()
+	if s.parity() != "N" {
+		bits++
+	}
+	return time.Duration(35*bits) * time.Second / time.Duration(10*s.baudRate())
+}
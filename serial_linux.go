@@ -0,0 +1,60 @@
+package modbus
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// open opens the serial device and applies the line settings in raw mode.
+func (s Serial) open(name string) (*os.File, error) {
+	if err := s.verify(); err != nil {
+		return nil, err
+	}
+	fd, err := syscall.Open(name, syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.configure(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// the non-blocking descriptor enables deadline support of the returned file
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+func (s Serial) configure(fd int) error {
+	speed := map[int]uint32{
+		1200:   syscall.B1200,
+		2400:   syscall.B2400,
+		4800:   syscall.B4800,
+		9600:   syscall.B9600,
+		19200:  syscall.B19200,
+		38400:  syscall.B38400,
+		57600:  syscall.B57600,
+		115200: syscall.B115200,
+		230400: syscall.B230400,
+	}[s.baudRate()]
+	// the speed is encoded inside the control flags, all other flags are cleared for raw mode
+	t := syscall.Termios{Cflag: speed | syscall.CREAD | syscall.CLOCAL}
+	t.Cc[syscall.VMIN], t.Cc[syscall.VTIME] = 1, 0
+	switch s.dataBits() {
+	case 7:
+		t.Cflag |= syscall.CS7
+	case 8:
+		t.Cflag |= syscall.CS8
+	}
+	switch s.parity() {
+	case "E":
+		t.Cflag |= syscall.PARENB
+	case "O":
+		t.Cflag |= syscall.PARENB | syscall.PARODD
+	}
+	if s.stopBits() == 2 {
+		t.Cflag |= syscall.CSTOPB
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
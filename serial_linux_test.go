@@ -0,0 +1,119 @@
+package modbus_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/GoAethereal/cancel"
+	"github.com/GoAethereal/modbus"
+)
+
+// pty opens a new pseudo-terminal pair, the master is returned as file and the slave by its path.
+func pty(t *testing.T) (master *os.File, slave string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals are unavailable: %v", err)
+	}
+	raw, err := master.SyscallConn()
+	if err != nil {
+		t.Fatalf("pty: %v", err)
+	}
+	var n, unlock uint32
+	raw.Control(func(fd uintptr) {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+			err = errno
+			return
+		}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+			err = errno
+		}
+	})
+	if err != nil {
+		master.Close()
+		t.Fatalf("pty: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+var (
+	rtuRequest  = []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03, 0x76, 0x87}
+	rtuResponse = []byte{0x11, 0x03, 0x06, 0xAE, 0x41, 0x56, 0x52, 0x43, 0x40, 0x49, 0xAD}
+)
+
+func TestSerialServer(t *testing.T) {
+	master, slave := pty(t)
+	defer master.Close()
+
+	s := &modbus.Server{Config: modbus.Config{
+		Mode:     "rtu",
+		Kind:     "serial",
+		Endpoint: slave,
+		Serial:   modbus.Serial{BaudRate: 9600, Silence: 50 * time.Millisecond},
+	}}
+
+	ctx := cancel.New()
+	defer serve(ctx, s, &modbus.Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, uid byte, address, quantity uint16) (res []byte, ex modbus.Exception) {
+			if uid != 0x11 || address != 0x6B || quantity != 3 {
+				t.Errorf("server received unexpected request for unit %v at address %v with quantity %v", uid, address, quantity)
+				return nil, modbus.IllegalDataAddress
+			}
+			return rtuResponse[3:9], 0
+		},
+	})()
+
+	time.Sleep(250 * time.Millisecond)
+
+	// the request is split in two parts, which must be joined as the gap is shorter than the silence
+	master.Write(rtuRequest[:3])
+	time.Sleep(5 * time.Millisecond)
+	master.Write(rtuRequest[3:])
+
+	master.SetReadDeadline(time.Now().Add(time.Second))
+	res := make([]byte, len(rtuResponse))
+	if _, err := io.ReadFull(master, res); err != nil {
+		t.Fatalf("serial server did not respond: %v", err)
+	}
+	if !bytes.Equal(res, rtuResponse) {
+		t.Fatalf("serial server returned invalid response; want % X; got: % X", rtuResponse, res)
+	}
+}
+
+func TestSerialClient(t *testing.T) {
+	master, slave := pty(t)
+	defer master.Close()
+
+	c := &modbus.Client{Config: modbus.Config{
+		Mode:     "rtu",
+		Kind:     "serial",
+		Endpoint: slave,
+		Serial:   modbus.Serial{BaudRate: 9600},
+	}}
+	defer c.Disconnect()
+
+	// the master side of the pseudo-terminal acts as slave device
+	go func() {
+		master.SetReadDeadline(time.Now().Add(time.Second))
+		req := make([]byte, len(rtuRequest))
+		if _, err := io.ReadFull(master, req); err != nil || !bytes.Equal(req, rtuRequest) {
+			t.Errorf("serial client sent invalid request % X: %v", req, err)
+			return
+		}
+		master.Write(rtuResponse)
+	}()
+
+	ctx := cancel.New().Timeout(time.Second)
+	res, err := c.ReadHoldingRegisters(ctx, 0x11, 0x6B, 3)
+	if err != nil {
+		t.Fatalf("serial client failed to read holding registers: %v", err)
+	}
+	if !bytes.Equal(res, rtuResponse[3:9]) {
+		t.Fatalf("serial client received invalid values; want % X; got: % X", rtuResponse[3:9], res)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package modbus
+
+import (
+	"errors"
+	"os"
+)
+
+// open is not yet supported on this platform.
+func (s Serial) open(_ string) (*os.File, error) {
+	return nil, errors.New("modbus: serial kind is not supported on this platform")
+}
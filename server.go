@@ -8,6 +8,12 @@ import (
 
 // Server is the go implementation of a modbus slave.
 // Once serving it will listen for incoming requests and forward them to the modbus.Handler h.
+// Diagnostics (0x08) are answered by the server itself, the same goes for the per unit
+// communication event counter (0x0B) and log (0x0C), unless the handler serves them.
+// Their state, including the listen only mode, is kept per connection, which on a serial
+// line is the line itself, while each client or peer of a network has its own.
+// If the Identity or Identification is set, the function code 0x11 respectively the
+// read device identification are handled alike.
 // Generally the intended use is as follows:
 //
 //	ctx := cancel.New()
@@ -22,19 +28,59 @@ import (
 //	log.Fatal(s.Serve(ctx,h))
 type Server struct {
 	Config
+	// Identity, if set, is reported on the function code 0x11 unless the handler serves it.
+	Identity *ServerID
+	// Identification, if set, answers the read device identification requests (function code 0x2B / MEI type 0x0E)
+	// unless the handler serves them. The objects must not be modified while serving.
+	Identification DeviceIdentification
+	// Units, if set, are the unit ids served in rtu and ascii mode, besides the broadcasts.
+	// Frames addressed to other units belong to other devices on the shared line, they are neither executed
+	// nor answered. The same goes for the responses of those devices, which carry their own unit id.
+	Units []byte
 	framer
+	mtx sync.Mutex
+}
+
+// ServerID is the identity of a server as returned by the function code 0x11 (report server id).
+type ServerID struct {
+	// ID identifies the type of the device.
+	ID byte
+	// Running is the run indicator status.
+	Running bool
+	// Data holds additional device specific information, limited to 249 bytes.
+	Data []byte
+}
+
+// report answers the function code 0x11.
+func (id *ServerID) report(req []byte) (res []byte, ex Exception) {
+	switch {
+	case len(req) != 0:
+		return nil, IllegalDataAddress
+	case len(id.Data) > 249:
+		return nil, SlaveDeviceFailure
+	}
+	run := byte(0x00)
+	if id.Running {
+		run = 0xFF
+	}
+	return put(3+len(id.Data), byte(2+len(id.Data)), id.ID, run, id.Data), 0
 }
 
 // Serve starts the modbus server and listens for incoming requests.
 // The Handler h is called for each inbound message.
 // h must be safe for use by multiple go routines.
+// Serve returns after ctx got canceled and the endpoint was released.
+// Calls on the same server are serialized, a second call waits until the first returned.
 func (s *Server) Serve(ctx cancel.Context, h Handler) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 	var wg sync.WaitGroup
-	l, err := s.listen(ctx)
+	f, err := s.Config.framer(ctx)
 	if err != nil {
 		return err
 	}
-	s.framer, err = s.Config.framer(ctx)
+	s.framer = f
+	l, err := s.listen(ctx, f, &wg)
 	if err != nil {
 		return err
 	}
@@ -61,6 +107,11 @@ func (s *Server) Serve(ctx cancel.Context, h Handler) error {
 func (s *Server) handle(ctx cancel.Context, c connection, h Handler) {
 	defer c.close()
 	var wg sync.WaitGroup
+	// expose the role of an authenticated client to the handler
+	if a, ok := c.(authenticated); ok {
+		ctx = withValue(ctx, roleKey, a.role())
+	}
+	d := &diagnostics{}
 
 	wait := c.rx(ctx, func(adu []byte, err error) (quit bool) {
 		if err != nil {
@@ -71,28 +122,19 @@ func (s *Server) handle(ctx cancel.Context, c connection, h Handler) {
 		wg.Add(1)
 		go func(adu []byte) {
 			defer wg.Done()
-			var res []byte
-			var ex Exception
 			uid, code, req, err := s.decode(adu)
-
-			switch {
-			case err != nil:
+			if err != nil {
+				d.count(&d.errors)
 				return
-			case code < 0x80:
-				res, ex = h.Handle(ctx, uid, code, req)
-			default:
-				ex = IllegalFunction
 			}
-
+			res, ex, reply := s.process(ctx, h, d, uid, code, req)
 			switch {
+			case !reply:
+				return
 			case ex != 0:
 				code |= 0x80
 				res = []byte{byte(ex)}
-			case len(res) > 252:
-				code |= 0x80
-				res = []byte{byte(SlaveDeviceFailure)}
 			}
-
 			res, _ = s.reply(uid, code, res, adu)
 			if err := c.tx(ctx, res); err != nil {
 				return
@@ -103,3 +145,90 @@ func (s *Server) handle(ctx cancel.Context, c connection, h Handler) {
 	<-wait
 	wg.Wait()
 }
+
+// process executes the request received on the connection with the diagnostics state d.
+// If no response must be sent reply is false.
+func (s *Server) process(ctx cancel.Context, h Handler, d *diagnostics, uid, code byte, req []byte) (res []byte, ex Exception, reply bool) {
+	d.count(&d.bus)
+	if !s.serves(uid) {
+		return nil, 0, false
+	}
+	d.count(&d.messages)
+	e := d.unit(uid)
+	e.receive(uid, d.listening())
+
+	reply, executed := true, false
+	switch {
+	case d.ignores(code, req):
+		reply = false
+	case code >= 0x80:
+		ex = IllegalFunction
+	default:
+		res, ex, reply = s.execute(ctx, h, d, e, uid, code, req)
+		executed = true
+	}
+	if ex == 0 && len(res) > 252 {
+		ex = SlaveDeviceFailure
+	}
+	// broadcasts are executed but never answered
+	if s.broadcast(uid) {
+		reply = false
+	}
+	e.send(code, ex, executed, reply, d.listening())
+
+	switch {
+	case !reply:
+		d.count(&d.silent)
+	case ex != 0:
+		d.count(&d.exceptions)
+		switch ex {
+		case SlaveDeviceBusy:
+			d.count(&d.busy)
+		case 0x07:
+			// negative acknowledge as defined by former revisions of the specification
+			d.count(&d.nak)
+		}
+	}
+	return res, ex, reply
+}
+
+// serves reports whether frames addressed to the unit are meant for the server.
+func (s *Server) serves(uid byte) bool {
+	if len(s.Units) == 0 || s.broadcast(uid) || s.Mode != "rtu" && s.Mode != "ascii" {
+		return true
+	}
+	for _, u := range s.Units {
+		if u == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// execute authorizes the request and answers it by the handler h, respectively by the server itself.
+// The server answers the diagnostics, as well as the communication event log and identity, unless the handler serves them.
+func (s *Server) execute(ctx cancel.Context, h Handler, d *diagnostics, e *events, uid, code byte, req []byte) (res []byte, ex Exception, reply bool) {
+	dispatch := h.Handle
+	if a, ok := h.(authorizer); ok {
+		if ex := a.authorize(ctx, uid, code); ex != 0 {
+			return nil, ex, true
+		}
+		dispatch = a.dispatch
+	}
+	if code == 0x08 {
+		return d.diagnose(uid, req)
+	}
+	res, ex = dispatch(ctx, uid, code, req)
+	switch {
+	case ex != IllegalFunction:
+	case code == 0x0B:
+		res, ex = e.commEventCounter(req)
+	case code == 0x0C:
+		res, ex = e.commEventLog(req, d.load(&d.bus))
+	case code == 0x11 && s.Identity != nil:
+		res, ex = s.Identity.report(req)
+	case code == 0x2B && s.Identification != nil && len(req) > 0 && req[0] == 0x0E:
+		res, ex = s.Identification.read(req[1:])
+	}
+	return res, ex, true
+}
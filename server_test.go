@@ -0,0 +1,128 @@
+package modbus
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/GoAethereal/cancel"
+)
+
+func TestAuthorize(t *testing.T) {
+	s := &Server{
+		Config:         Config{Mode: "tcp"},
+		Identity:       &ServerID{ID: 0x2A},
+		Identification: DeviceIdentification{ObjectVendorName: "GoAethereal"},
+	}
+	h := &Mux{
+		// only reading holding registers is permitted
+		Authorize: func(_ cancel.Context, _, code byte) (ex Exception) {
+			if code != 0x03 {
+				return IllegalFunction
+			}
+			return 0
+		},
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex Exception) {
+			return make([]byte, 2*quantity), 0
+		},
+	}
+	ctx, d := cancel.New(), &diagnostics{}
+	defer ctx.Cancel()
+
+	if _, ex, _ := s.process(ctx, h, d, 1, 0x03, []byte{0x00, 0x00, 0x00, 0x01}); ex != 0 {
+		t.Fatalf("authorized request returned unexpected exception: %v", ex)
+	}
+	// the requests answered by the server itself are rejected alike
+	testCases := map[byte][]byte{
+		0x08: {0x00, 0x00, 0x12, 0x34},
+		0x0B: nil,
+		0x0C: nil,
+		0x11: nil,
+		0x2B: {0x0E, 0x01, 0x00},
+	}
+	for code, req := range testCases {
+		if res, ex, _ := s.process(ctx, h, d, 1, code, req); ex != IllegalFunction {
+			t.Fatalf("unauthorized request %02X returned unexpected response; want %v; got: % X, %v", code, IllegalFunction, res, ex)
+		}
+	}
+}
+
+func TestListenOnlyEventCounter(t *testing.T) {
+	s := &Server{Config: Config{Mode: "tcp"}}
+	h := &Mux{
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex Exception) {
+			return make([]byte, 2*quantity), 0
+		},
+	}
+	ctx, d := cancel.New(), &diagnostics{}
+	defer ctx.Cancel()
+
+	read := []byte{0x00, 0x00, 0x00, 0x01}
+	if _, ex, _ := s.process(ctx, h, d, 1, 0x03, read); ex != 0 {
+		t.Fatalf("read holding registers returned unexpected exception: %v", ex)
+	}
+	if _, _, reply := s.process(ctx, h, d, 1, 0x08, []byte{0x00, 0x04, 0x00, 0x00}); reply {
+		t.Fatalf("force listen only mode was answered")
+	}
+	if _, _, reply := s.process(ctx, h, d, 1, 0x03, read); reply {
+		t.Fatalf("read holding registers was answered in listen only mode")
+	}
+	// leave the listen only mode without the restart communications option, which would clear the counter
+	atomic.StoreUint32(&d.listen, 0)
+
+	// the read and the force listen only mode are counted, the dropped read is not
+	res, ex, _ := s.process(ctx, h, d, 1, 0x0B, nil)
+	if want := []byte{0x00, 0x00, 0x00, 0x02}; ex != 0 || !bytes.Equal(res, want) {
+		t.Fatalf("get comm event counter returned unexpected response; want % X; got: % X, %v", want, res, ex)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	var calls []byte
+	h := &Mux{
+		WriteSingleRegister: func(_ cancel.Context, uid byte, _, _ uint16) (ex Exception) {
+			calls = append(calls, uid)
+			return 0
+		},
+	}
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	write := []byte{0x00, 0x01, 0x00, 0x02}
+	testCases := []struct {
+		mode            string
+		uid, code       byte
+		req             []byte
+		executed, reply bool
+	}{
+		{"rtu", 1, 0x06, write, true, true},
+		{"ascii", 2, 0x06, write, true, true},
+		// request to another device on the line
+		{"rtu", 3, 0x06, write, false, false},
+		// response of another device on the line
+		{"ascii", 3, 0x03, []byte{0x02, 0x00, 0x2A}, false, false},
+		// broadcasts are executed by all devices
+		{"rtu", 0, 0x06, write, true, false},
+		// the units only apply to the serial line framings
+		{"tcp", 3, 0x06, write, true, true},
+	}
+	for _, tc := range testCases {
+		s, d := &Server{Config: Config{Mode: tc.mode}, Units: []byte{1, 2}}, &diagnostics{}
+		calls = calls[:0]
+		_, ex, reply := s.process(ctx, h, d, tc.uid, tc.code, tc.req)
+		if ex != 0 || reply != tc.reply {
+			t.Fatalf("request %02X to unit %v in %v mode returned unexpected result; want reply %v; got: %v, %v", tc.code, tc.uid, tc.mode, tc.reply, reply, ex)
+		}
+		if executed := len(calls) == 1; executed != tc.executed {
+			t.Fatalf("request %02X to unit %v in %v mode executed unexpectedly; want %v; got: %v", tc.code, tc.uid, tc.mode, tc.executed, executed)
+		}
+		// frames to other units are seen on the bus, but aren´t messages of the server
+		want := uint16(0)
+		if tc.executed {
+			want = 1
+		}
+		if bus, messages := d.load(&d.bus), d.load(&d.messages); bus != 1 || messages != want {
+			t.Fatalf("request %02X to unit %v in %v mode counted unexpectedly; want 1, %v; got: %v, %v", tc.code, tc.uid, tc.mode, want, bus, messages)
+		}
+	}
+}
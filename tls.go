@@ -0,0 +1,91 @@
+package modbus
+
+import (
+	"crypto/tls"
+	"encoding/asn1"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// RoleOID identifies the certificate extension carrying the role of a client as defined
+// by the modbus/TCP security specification. The value is encoded as ASN.1 UTF8String.
+var RoleOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 50316, 802, 1}
+
+// Role returns the role of the client as transmitted by its certificate in the tls kind.
+// The context handed to a Handler by the modbus.Server carries the role, which allows
+// the authorization of requests (see Mux.Authorize).
+// If the client did not provide a role ok is false.
+func Role(ctx cancel.Context) (role string, ok bool) {
+	role, ok = value(ctx, roleKey).(string)
+	return role, ok
+}
+
+// authenticated is implemented by connections knowing the role of their peer.
+type authenticated interface {
+	role() string
+}
+
+var _ authenticated = (*secured)(nil)
+
+// secured is a network connection with a client authenticated by its role.
+type secured struct {
+	*network
+	r string
+}
+
+func (c *secured) role() string {
+	return c.r
+}
+
+// handshake completes the tls handshake of an accepted connection.
+// If the client certificate carries a role the returned connection is authenticated.
+func handshake(con *tls.Conn, f framer) (connection, error) {
+	con.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := con.Handshake(); err != nil {
+		return nil, err
+	}
+	con.SetDeadline(time.Time{})
+	role, ok := roleOf(con.ConnectionState())
+	c, err := (&network{con: con, buf: f.buffer(), split: f.split}).init()
+	if err != nil || !ok {
+		return c, err
+	}
+	return &secured{network: c.(*network), r: role}, nil
+}
+
+// roleOf extracts the role from the certificate of the peer.
+func roleOf(state tls.ConnectionState) (role string, ok bool) {
+	if len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	for _, ext := range state.PeerCertificates[0].Extensions {
+		if !ext.Id.Equal(RoleOID) {
+			continue
+		}
+		if _, err := asn1.UnmarshalWithParams(ext.Value, &role, "utf8"); err == nil {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// tlsConfig returns the tls configuration, whereby the mutual authentication
+// and the protocol version required by the specification are enforced.
+// A client authentication not requiring a certificate is raised to the one requiring it.
+func (cfg Config) tlsConfig() *tls.Config {
+	c := &tls.Config{}
+	if cfg.TLS != nil {
+		c = cfg.TLS.Clone()
+	}
+	switch c.ClientAuth {
+	case tls.NoClientCert, tls.VerifyClientCertIfGiven:
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	case tls.RequestClientCert:
+		c.ClientAuth = tls.RequireAnyClientCert
+	}
+	if c.MinVersion == 0 {
+		c.MinVersion = tls.VersionTLS12
+	}
+	return c
+}
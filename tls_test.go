@@ -0,0 +1,124 @@
+package modbus_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+	"github.com/GoAethereal/modbus"
+)
+
+// certificate issues a new certificate signed by the parent, which is self-signed if nil.
+// A non-empty role is embedded as modbus role extension.
+func certificate(t *testing.T, parent *tls.Certificate, name, role string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("certificate: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if role != "" {
+		value, _ := asn1.MarshalWithParams(role, "utf8")
+		tmpl.ExtraExtensions = []pkix.Extension{{Id: modbus.RoleOID, Value: value}}
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestTLS(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ca := certificate(t, nil, "ca", "")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	cfg := func(cert *tls.Certificate) modbus.Config {
+		return modbus.Config{Mode: "tcp", Kind: "tls", Endpoint: "localhost:1340", TLS: &tls.Config{
+			Certificates: []tls.Certificate{*cert},
+			RootCAs:      pool,
+			ClientCAs:    pool,
+		}}
+	}
+
+	s := &modbus.Server{Config: cfg(certificate(t, ca, "server", ""))}
+	// the mutual authentication is enforced even if the configuration asks for less
+	s.TLS.ClientAuth = tls.VerifyClientCertIfGiven
+
+	ctx := cancel.New()
+	defer serve(ctx, s, &modbus.Mux{
+		// operators may only read, all others are rejected
+		Authorize: func(ctx cancel.Context, _, code byte) (ex modbus.Exception) {
+			if role, ok := modbus.Role(ctx); ok && role == "operator" && code == 0x03 {
+				return 0
+			}
+			return modbus.IllegalFunction
+		},
+		ReadHoldingRegisters: func(_ cancel.Context, _ byte, _, quantity uint16) (res []byte, ex modbus.Exception) {
+			return make([]byte, 2*quantity), 0
+		},
+		WriteSingleRegister: func(_ cancel.Context, _ byte, _, _ uint16) (ex modbus.Exception) {
+			t.Errorf("server executed unauthorized write single register")
+			return 0
+		},
+	})()
+
+	time.Sleep(250 * time.Millisecond)
+
+	operator := &modbus.Client{Config: cfg(certificate(t, ca, "operator", "operator"))}
+	defer operator.Disconnect()
+	if _, err := operator.ReadHoldingRegisters(ctx, 1, 0, 2); err != nil {
+		t.Fatalf("operator failed to read holding registers: %v", err)
+	}
+	if err := operator.WriteSingleRegister(ctx, 1, 0, 1); err != modbus.IllegalFunction {
+		t.Fatalf("operator write single register returned unexpected error; want %v; got: %v", modbus.IllegalFunction, err)
+	}
+
+	anonymous := &modbus.Client{Config: cfg(certificate(t, ca, "anonymous", ""))}
+	defer anonymous.Disconnect()
+	if _, err := anonymous.ReadHoldingRegisters(ctx, 1, 0, 2); err != modbus.IllegalFunction {
+		t.Fatalf("client without role read holding registers returned unexpected error; want %v; got: %v", modbus.IllegalFunction, err)
+	}
+
+	nameless := &modbus.Client{Config: cfg(certificate(t, ca, "nameless", ""))}
+	nameless.TLS.Certificates = nil
+	defer nameless.Disconnect()
+	if _, err := nameless.ReadHoldingRegisters(cancel.New().Timeout(time.Second), 1, 0, 2); err == nil || errors.As(err, new(modbus.Exception)) {
+		t.Fatalf("client without certificate was answered by the server: %v", err)
+	}
+
+	// clients without a trusted certificate are refused during the handshake
+	untrusted := &modbus.Client{Config: cfg(certificate(t, nil, "untrusted", "operator"))}
+	defer untrusted.Disconnect()
+	if _, err := untrusted.ReadHoldingRegisters(cancel.New().Timeout(time.Second), 1, 0, 2); err == nil {
+		t.Fatalf("client with untrusted certificate read holding registers")
+	}
+}
@@ -0,0 +1,60 @@
+package modbus
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/GoAethereal/cancel"
+)
+
+// window limits the number of concurrent transactions.
+// Waiting transactions are admitted in the order of their arrival.
+type window struct {
+	mtx     sync.Mutex
+	active  int
+	waiting list.List
+}
+
+// acquire blocks until one of the limit slots is available or ctx is canceled.
+// A successful acquire must be followed by a release.
+func (w *window) acquire(ctx cancel.Context, limit int) error {
+	w.mtx.Lock()
+	if w.active < limit && w.waiting.Len() == 0 {
+		w.active++
+		w.mtx.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	e := w.waiting.PushBack(ready)
+	w.mtx.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		w.mtx.Lock()
+		select {
+		case <-ready:
+			// the slot was handed over in the meantime, pass it on
+			w.mtx.Unlock()
+			w.release()
+		default:
+			w.waiting.Remove(e)
+			w.mtx.Unlock()
+		}
+		return context.Canceled
+	}
+}
+
+// release frees the slot, which is directly handed over to the longest waiting transaction.
+func (w *window) release() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if e := w.waiting.Front(); e != nil {
+		w.waiting.Remove(e)
+		close(e.Value.(chan struct{}))
+		return
+	}
+	w.active--
+}
@@ -0,0 +1,52 @@
+package modbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoAethereal/cancel"
+)
+
+func TestWindowOrder(t *testing.T) {
+	var w window
+	ctx := cancel.New()
+	defer ctx.Cancel()
+
+	if err := w.acquire(ctx, 1); err != nil {
+		t.Fatalf("acquire of free window failed: %v", err)
+	}
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			if err := w.acquire(ctx, 1); err != nil {
+				t.Errorf("acquire %v failed: %v", i, err)
+				return
+			}
+			order <- i
+			w.release()
+		}(i)
+		// ensure the order of arrival
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.release()
+	for want := 0; want < 3; want++ {
+		if got := <-order; got != want {
+			t.Fatalf("window admitted transactions out of order; want %v; got: %v", want, got)
+		}
+	}
+}
+
+func TestWindowCancel(t *testing.T) {
+	var w window
+	if err := w.acquire(cancel.New(), 1); err != nil {
+		t.Fatalf("acquire of free window failed: %v", err)
+	}
+	if err := w.acquire(cancel.New().Timeout(50*time.Millisecond), 1); err == nil {
+		t.Fatalf("acquire of exhausted window succeeded")
+	}
+	// the canceled transaction must not hold on to the slot
+	w.release()
+	if err := w.acquire(cancel.New().Timeout(50*time.Millisecond), 1); err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+}